@@ -2,4 +2,10 @@ module Go_Zinx
 
 go 1.24
 
-require github.com/emirpasic/gods/v2 v2.0.0-alpha
+require (
+	github.com/emirpasic/gods/v2 v2.0.0-alpha
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.36.9
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
@@ -1,8 +1,25 @@
 package main
 
-import "Go_Zinx/znet"
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/znet"
+	"flag"
+	"fmt"
+	"os"
+)
 
 func main() {
+	// 通过 -config 指定配置文件路径（也可以使用 ZINX_CONFIG 环境变量）
+	configFile := flag.String("config", "", "path of the zinx config file")
+	flag.Parse()
+
+	if *configFile != "" {
+		if err := utils.GlobalObject.LoadConfig(*configFile); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
 	// 创建一个服务器
 	s := znet.NewServer()
 
@@ -5,9 +5,19 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"io"
 )
 
+// DataPack 定长消息头的封包方式
+// 默认格式为小端序的 [DataLen 4字节][MsgId 4字节][Data]
+// 可以通过不同的构造函数调整字节序、字段顺序和长度字段的宽度
 type DataPack struct {
+	// 字节序
+	byteOrder binary.ByteOrder
+	// 头部是否为 [MsgId][DataLen] 顺序
+	idFirst bool
+	// 长度字段的字节数，2或4
+	lenSize uint32
 }
 
 // message 是一个实现了zinterface.IMessage接口的结构体
@@ -18,6 +28,9 @@ type message struct {
 	Id      uint32
 	DataLen uint32
 	Data    []byte
+	Seq     uint32
+	Flags   uint8
+	Status  uint16
 }
 
 func (m *message) GetMsgId() uint32 {
@@ -44,31 +57,137 @@ func (m *message) SetData(data []byte) {
 	m.Data = data
 }
 
+func (m *message) GetSeq() uint32 {
+	return m.Seq
+}
+
+func (m *message) SetSeq(seq uint32) {
+	m.Seq = seq
+}
+
+func (m *message) GetFlags() uint8 {
+	return m.Flags
+}
+
+func (m *message) SetFlags(flags uint8) {
+	m.Flags = flags
+}
+
+func (m *message) GetStatus() uint16 {
+	return m.Status
+}
+
+func (m *message) SetStatus(status uint16) {
+	m.Status = status
+}
+
+// NewDataPackUtil 默认的封包方式：小端序 [DataLen 4字节][MsgId 4字节]
 func NewDataPackUtil() *DataPack {
-	return &DataPack{}
+	return NewDataPack(binary.LittleEndian, false, 4)
+}
+
+// NewBigEndianDataPack 大端序 [DataLen 4字节][MsgId 4字节]
+func NewBigEndianDataPack() *DataPack {
+	return NewDataPack(binary.BigEndian, false, 4)
+}
+
+// NewIdFirstDataPack 小端序 [MsgId 4字节][DataLen 4字节]
+func NewIdFirstDataPack() *DataPack {
+	return NewDataPack(binary.LittleEndian, true, 4)
+}
+
+// NewShortLenDataPack 小端序 [DataLen 2字节][MsgId 4字节]，单个消息体最大 65535 字节
+func NewShortLenDataPack() *DataPack {
+	return NewDataPack(binary.LittleEndian, false, 2)
+}
+
+// NewDataPack 自定义字节序、字段顺序和长度字段宽度（2或4字节）的封包方式
+func NewDataPack(order binary.ByteOrder, idFirst bool, lenSize uint32) *DataPack {
+	if lenSize != 2 {
+		lenSize = 4
+	}
+	return &DataPack{
+		byteOrder: order,
+		idFirst:   idFirst,
+		lenSize:   lenSize,
+	}
+}
+
+// order 获取字节序，零值DataPack使用小端序
+func (dp *DataPack) order() binary.ByteOrder {
+	if dp.byteOrder == nil {
+		return binary.LittleEndian
+	}
+	return dp.byteOrder
+}
+
+// sizeOfLen 获取长度字段的字节数，零值DataPack使用4字节
+func (dp *DataPack) sizeOfLen() uint32 {
+	if dp.lenSize == 2 {
+		return 2
+	}
+	return 4
 }
 
 func (dp *DataPack) GetHeadLen() uint32 {
-	return 4 + 4
+	return dp.sizeOfLen() + 4
 }
 
 func (dp *DataPack) Pack(msg zinterface.IMessage) ([]byte, error) {
 	// 定义一个缓冲
-	dataBuff := bytes.NewBuffer([]byte{})
+	dataBuff := bytes.NewBuffer(make([]byte, 0, dp.GetHeadLen()+msg.GetDataLen()))
 
-	if err := binary.Write(dataBuff, binary.LittleEndian, msg.GetDataLen()); err != nil {
-		return nil, err
+	if dp.idFirst {
+		if err := binary.Write(dataBuff, dp.order(), msg.GetMsgId()); err != nil {
+			return nil, err
+		}
+		if err := dp.writeLen(dataBuff, msg.GetDataLen()); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := dp.writeLen(dataBuff, msg.GetDataLen()); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(dataBuff, dp.order(), msg.GetMsgId()); err != nil {
+			return nil, err
+		}
 	}
-	if err := binary.Write(dataBuff, binary.LittleEndian, msg.GetMsgId()); err != nil {
-		return nil, err
-	}
-	if err := binary.Write(dataBuff, binary.LittleEndian, msg.GetData()); err != nil {
+
+	if err := binary.Write(dataBuff, dp.order(), msg.GetData()); err != nil {
 		return nil, err
 	}
 
 	return dataBuff.Bytes(), nil
 }
 
+// writeLen 按长度字段的宽度写入数据长度
+func (dp *DataPack) writeLen(w io.Writer, dataLen uint32) error {
+	if dp.sizeOfLen() == 2 {
+		if dataLen > 0xFFFF {
+			return errors.New("msg Data Is Too Large for 2-byte length")
+		}
+		return binary.Write(w, dp.order(), uint16(dataLen))
+	}
+	return binary.Write(w, dp.order(), dataLen)
+}
+
+// readLen 按长度字段的宽度读取数据长度
+func (dp *DataPack) readLen(r io.Reader) (uint32, error) {
+	if dp.sizeOfLen() == 2 {
+		var dataLen uint16
+		if err := binary.Read(r, dp.order(), &dataLen); err != nil {
+			return 0, err
+		}
+		return uint32(dataLen), nil
+	}
+	var dataLen uint32
+	if err := binary.Read(r, dp.order(), &dataLen); err != nil {
+		return 0, err
+	}
+	return dataLen, nil
+}
+
+// Unpack 解析消息头，消息体长度由 ReadMessage 检查
 func (dp *DataPack) Unpack(data []byte) (zinterface.IMessage, error) {
 	// 创建一个输入二进制数据的IO-Reader
 	dataBuff := bytes.NewReader(data)
@@ -76,18 +195,87 @@ func (dp *DataPack) Unpack(data []byte) (zinterface.IMessage, error) {
 	// 创建一个实现了zinterface.IMessage接口的结构体
 	msg := &message{Id: 0, DataLen: 0, Data: nil}
 
-	// 先读Head-Len
-	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.DataLen); err != nil {
+	var err error
+	if dp.idFirst {
+		if err = binary.Read(dataBuff, dp.order(), &msg.Id); err != nil {
+			return nil, err
+		}
+		if msg.DataLen, err = dp.readLen(dataBuff); err != nil {
+			return nil, err
+		}
+	} else {
+		// 先读Head-Len
+		if msg.DataLen, err = dp.readLen(dataBuff); err != nil {
+			return nil, err
+		}
+		if err = binary.Read(dataBuff, dp.order(), &msg.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// ErrPackageTooLarge 消息体长度超过允许的最大值
+var ErrPackageTooLarge = errors.New("msg Data Is Too Large")
+
+// checkDataLen 检查消息体长度是否超过maxSize，maxSize为0时不限制
+func checkDataLen(dataLen uint32, maxSize uint32) error {
+	if maxSize > 0 && dataLen > maxSize {
+		return ErrPackageTooLarge
+	}
+	return nil
+}
+
+// limitedStreamDataPack 可以在分配消息体之前检查长度的流式封包方式
+type limitedStreamDataPack interface {
+	readMsg(r io.Reader, maxSize uint32) (zinterface.IMessage, error)
+}
+
+// ReadMessage 从数据流中读取一个完整的消息，消息体长度不超过配置的 MaxPackageSize
+func ReadMessage(r io.Reader, dp zinterface.IDataPack) (zinterface.IMessage, error) {
+	return ReadMessageLimit(r, dp, GlobalObject.MaxPackageSize)
+}
+
+// ReadMessageLimit 从数据流中读取一个完整的消息，消息体长度超过maxSize时返回 ErrPackageTooLarge
+// maxSize为0时不限制。实现了 zinterface.IStreamDataPack 的封包方式直接从流中解析，
+// 否则先读取定长的消息头，再根据消息头中的长度读取消息体
+func ReadMessageLimit(r io.Reader, dp zinterface.IDataPack, maxSize uint32) (zinterface.IMessage, error) {
+	if ldp, ok := dp.(limitedStreamDataPack); ok {
+		return ldp.readMsg(r, maxSize)
+	}
+	if sdp, ok := dp.(zinterface.IStreamDataPack); ok {
+		msg, err := sdp.ReadMsg(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDataLen(msg.GetDataLen(), maxSize); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	headData := make([]byte, dp.GetHeadLen())
+	if _, err := io.ReadFull(r, headData); err != nil {
 		return nil, err
 	}
 
-	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.Id); err != nil {
+	msg, err := dp.Unpack(headData)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDataLen(msg.GetDataLen(), maxSize); err != nil {
 		return nil, err
 	}
 
-	if GlobalObject.MaxPackageSize > 0 && msg.DataLen > GlobalObject.MaxPackageSize {
-		return nil, errors.New("msg Data Is Too Large")
+	var data []byte
+	if msg.GetDataLen() > 0 {
+		data = make([]byte, msg.GetDataLen())
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
 	}
+	msg.SetData(data)
 
 	return msg, nil
 }
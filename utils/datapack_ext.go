@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"Go_Zinx/zinterface"
+	"bytes"
+	"encoding/binary"
+)
+
+// ExtHeadLen 扩展消息头长度
+const ExtHeadLen = 4 + 4 + 4 + 1 + 2
+
+// ExtDataPack 扩展消息头的封包方式
+// 格式为小端序的 [DataLen 4字节][MsgId 4字节][Seq 4字节][Flags 1字节][Status 2字节][Data]
+// 序列号用于在多个请求并发时关联请求和响应
+type ExtDataPack struct {
+}
+
+func NewExtDataPack() *ExtDataPack {
+	return &ExtDataPack{}
+}
+
+// CarriesSeq 扩展消息头会传输序列号
+func (dp *ExtDataPack) CarriesSeq() bool {
+	return true
+}
+
+// CarriesSeq 判断封包方式是否传输序列号、标志位等扩展字段
+func CarriesSeq(dp zinterface.IDataPack) bool {
+	sdp, ok := dp.(interface{ CarriesSeq() bool })
+	return ok && sdp.CarriesSeq()
+}
+
+func (dp *ExtDataPack) GetHeadLen() uint32 {
+	return ExtHeadLen
+}
+
+func (dp *ExtDataPack) Pack(msg zinterface.IMessage) ([]byte, error) {
+	buf := make([]byte, 0, ExtHeadLen+msg.GetDataLen())
+	buf = binary.LittleEndian.AppendUint32(buf, msg.GetDataLen())
+	buf = binary.LittleEndian.AppendUint32(buf, msg.GetMsgId())
+	buf = binary.LittleEndian.AppendUint32(buf, msg.GetSeq())
+	buf = append(buf, msg.GetFlags())
+	buf = binary.LittleEndian.AppendUint16(buf, msg.GetStatus())
+	buf = append(buf, msg.GetData()...)
+	return buf, nil
+}
+
+func (dp *ExtDataPack) Unpack(data []byte) (zinterface.IMessage, error) {
+	dataBuff := bytes.NewReader(data)
+
+	msg := &message{}
+	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.DataLen); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.Id); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.Seq); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.Flags); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.Status); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"Go_Zinx/zinterface"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// VarintDataPack 变长消息头的封包方式
+// 格式为 [DataLen uvarint][MsgId 4字节小端][Data]，小消息可以节省头部开销
+type VarintDataPack struct {
+}
+
+func NewVarintDataPack() *VarintDataPack {
+	return &VarintDataPack{}
+}
+
+// GetHeadLen 返回消息头的最大长度
+// 消息头长度不固定，读取时应使用 ReadMsg 或 utils.ReadMessage
+func (dp *VarintDataPack) GetHeadLen() uint32 {
+	return binary.MaxVarintLen32 + 4
+}
+
+func (dp *VarintDataPack) Pack(msg zinterface.IMessage) ([]byte, error) {
+	buf := make([]byte, 0, dp.GetHeadLen()+msg.GetDataLen())
+	buf = binary.AppendUvarint(buf, uint64(msg.GetDataLen()))
+	buf = binary.LittleEndian.AppendUint32(buf, msg.GetMsgId())
+	buf = append(buf, msg.GetData()...)
+	return buf, nil
+}
+
+// Unpack 从包含完整消息头的数据中解析出消息头
+func (dp *VarintDataPack) Unpack(data []byte) (zinterface.IMessage, error) {
+	return dp.unpackHead(bytes.NewReader(data))
+}
+
+// ReadMsg 从数据流中读取一个完整的消息，消息体长度不超过配置的 MaxPackageSize
+func (dp *VarintDataPack) ReadMsg(r io.Reader) (zinterface.IMessage, error) {
+	return dp.readMsg(r, GlobalObject.MaxPackageSize)
+}
+
+// readMsg 读取一个完整的消息，在分配消息体之前检查长度
+func (dp *VarintDataPack) readMsg(r io.Reader, maxSize uint32) (zinterface.IMessage, error) {
+	msg, err := dp.unpackHead(&byteReader{r: r})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDataLen(msg.DataLen, maxSize); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if msg.GetDataLen() > 0 {
+		data = make([]byte, msg.GetDataLen())
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+	}
+	msg.SetData(data)
+
+	return msg, nil
+}
+
+// headReader 解析变长消息头需要的读取能力
+type headReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func (dp *VarintDataPack) unpackHead(r headReader) (*message, error) {
+	dataLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if dataLen > 0xFFFFFFFF {
+		return nil, errors.New("msg Data Is Too Large")
+	}
+
+	msg := &message{DataLen: uint32(dataLen)}
+	if err := binary.Read(r, binary.LittleEndian, &msg.Id); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// byteReader 为 io.Reader 提供逐字节读取的能力，不会多读数据
+type byteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+func (br *byteReader) Read(p []byte) (int, error) {
+	return br.r.Read(p)
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(br.r, br.buf[:]); err != nil {
+		return 0, err
+	}
+	return br.buf[0], nil
+}
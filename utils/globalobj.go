@@ -2,14 +2,67 @@ package utils
 
 import (
 	"Go_Zinx/zinterface"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConfigEnvKey 指定配置文件路径的环境变量名
+const ConfigEnvKey = "ZINX_CONFIG"
+
+// DefaultConfigPath 默认配置文件路径（相对于工作目录）
+const DefaultConfigPath = "resource/config.json"
+
+// 工作池的调度模式
+const (
+	// DispatchElastic 请求交给任意空闲的工作线程，工作线程数在核心数和最大数之间伸缩
+	DispatchElastic = "elastic"
+	// DispatchOrdered 请求按ConnID（或自定义的key）分片到固定的工作线程，同一连接的请求按顺序处理
+	DispatchOrdered = "ordered"
+)
+
+// 工作池请求队列满时的处理策略
+const (
+	// OverflowBlock 阻塞读协程直到队列有空位，利用TCP流控向客户端施加背压
+	OverflowBlock = "block"
+	// OverflowDropOldest 丢弃队列中最早的请求，为新请求腾出空间
+	OverflowDropOldest = "drop_oldest"
+	// OverflowDropNewest 丢弃新请求，并向客户端回复 StatusUnavailable 的错误响应
+	OverflowDropNewest = "drop_newest"
+	// OverflowClose 丢弃新请求并关闭连接
+	OverflowClose = "close"
 )
 
 // WorkerPoolConfig 工作池配置
 type WorkerPoolConfig struct {
-	CoreWorkers  uint32 // 核心工作线程数
-	MaxWorkers   uint32 // 最大工作线程数
-	QueueSize    uint32 // 请求队列大小
-	IdleTimeout  uint32 // 非核心工作线程空闲超时时间（秒）
+	CoreWorkers uint32 // 核心工作线程数
+	MaxWorkers  uint32 // 最大工作线程数，ordered 模式下为分片数
+	QueueSize   uint32 // 请求队列大小
+	IdleTimeout uint32 // 非核心工作线程空闲超时时间（秒）
+	Mode        string // 调度模式，elastic（默认）或 ordered
+	// 请求队列满时的处理策略，默认 drop_newest
+	OverflowPolicy string
+	// 高/低优先级队列大小，为0时与 QueueSize 相同
+	// 优先级只在 elastic 模式下生效，ordered 模式按连接顺序处理，不能设置以下三项
+	HighQueueSize uint32
+	LowQueueSize  uint32
+	// 低优先级队列连续被跳过多少次后优先处理一个它的请求，为0时使用默认值
+	StarvationLimit uint32
+}
+
+// TLSConfig 服务器的TLS配置，CertFile 为空时不使用TLS
+type TLSConfig struct {
+	CertFile string // 服务器证书文件（PEM）
+	KeyFile  string // 服务器私钥文件（PEM）
+	// 客户端CA证书文件（PEM），不为空时验证客户端证书（mTLS）
+	ClientCAFile string
+	// 是否要求客户端必须提供证书，为false时只验证客户端提供的证书
+	RequireClientCert bool
+	// 检查证书文件是否修改的间隔（秒），修改后自动重新加载；为0时不检查
+	ReloadInterval uint32
 }
 
 // 存储配置参数类
@@ -21,19 +74,302 @@ type GlobalObj struct {
 	Version        string
 	MaxConn        int
 	MaxPackageSize uint32
+	// 每个连接发送队列的长度，必须大于0
+	SendQueueSize uint32
+	// 每个连接写缓冲区的大小（字节），队列中的多个消息合并为一次系统调用
+	WriteBufferSize uint32
 	// 日志相关配置
 	LogLevel int    // 日志级别
 	LogFile  string // 日志文件路径
 	// 工作池配置
 	WorkerPool WorkerPoolConfig
+	// TLS配置
+	TLS TLSConfig
+
+	// 配置文件路径
+	ConfFilePath string
+
+	// 最近一次加载配置文件的错误
+	loadErr error
+}
+
+// LoadErr 获取包初始化时加载配置文件的错误，加载失败时使用默认配置
+// 之后 LoadConfig/Reload 成功会清除该错误
+func (g *GlobalObj) LoadErr() error {
+	return g.loadErr
 }
 
 var GlobalObject *GlobalObj
 
+// workerPoolFileConfig 配置文件中的工作池配置
+// 使用指针字段以区分"未填写"和"零值"
+type workerPoolFileConfig struct {
+	CoreWorkers     *uint32
+	MaxWorkers      *uint32
+	QueueSize       *uint32
+	IdleTimeout     *uint32
+	Mode            *string
+	OverflowPolicy  *string
+	HighQueueSize   *uint32
+	LowQueueSize    *uint32
+	StarvationLimit *uint32
+}
+
+// fileConfig 配置文件的结构
+type fileConfig struct {
+	Host            *string
+	TCPPort         *int
+	Name            *string
+	Version         *string
+	MaxConn         *int
+	MaxPackageSize  *uint32
+	SendQueueSize   *uint32
+	WriteBufferSize *uint32
+	LogLevel        *int
+	LogFile         *string
+	WorkerPool      *workerPoolFileConfig
+	// TLS配置的字段都是可选的，填写时整体替换
+	TLS *TLSConfig
+}
+
 // 解析JSON参数
-func (g *GlobalObj) Reload() {
-	// 暂时使用默认配置，后续可以添加简单的JSON配置读取
-	// 这里保留方法是为了兼容现有代码
+// 从 ConfFilePath 读取配置，校验通过后覆盖当前配置并重新初始化日志
+func (g *GlobalObj) Reload() error {
+	if g.ConfFilePath == "" {
+		return errors.New("config file path is empty")
+	}
+
+	data, err := os.ReadFile(g.ConfFilePath)
+	if err != nil {
+		return fmt.Errorf("read config file %s error: %w", g.ConfFilePath, err)
+	}
+
+	var fc fileConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fc); err != nil {
+		return fmt.Errorf("parse config file %s error: %w", g.ConfFilePath, err)
+	}
+
+	// 在副本上合并配置，校验通过后再生效
+	conf := *g
+	if err := fc.mergeInto(&conf); err != nil {
+		return fmt.Errorf("config file %s: %w", g.ConfFilePath, err)
+	}
+	if err := conf.Validate(); err != nil {
+		return fmt.Errorf("config file %s: %w", g.ConfFilePath, err)
+	}
+
+	conf.loadErr = nil
+	*g = conf
+	g.applyLogger()
+	return nil
+}
+
+// LoadConfig 从指定路径加载配置
+func (g *GlobalObj) LoadConfig(path string) error {
+	old := g.ConfFilePath
+	g.ConfFilePath = path
+	if err := g.Reload(); err != nil {
+		g.ConfFilePath = old
+		return err
+	}
+	return nil
+}
+
+// mergeInto 将配置文件中的字段合并到conf中，缺少必填字段时返回错误
+func (fc *fileConfig) mergeInto(conf *GlobalObj) error {
+	var missing []string
+
+	if fc.Host == nil {
+		missing = append(missing, "Host")
+	} else {
+		conf.Host = *fc.Host
+	}
+	if fc.TCPPort == nil {
+		missing = append(missing, "TCPPort")
+	} else {
+		conf.TCPPort = *fc.TCPPort
+	}
+	if fc.MaxConn == nil {
+		missing = append(missing, "MaxConn")
+	} else {
+		conf.MaxConn = *fc.MaxConn
+	}
+	if fc.MaxPackageSize == nil {
+		missing = append(missing, "MaxPackageSize")
+	} else {
+		conf.MaxPackageSize = *fc.MaxPackageSize
+	}
+
+	// 可选字段，未填写时保留默认值
+	if fc.Name != nil {
+		conf.Name = *fc.Name
+	}
+	if fc.Version != nil {
+		conf.Version = *fc.Version
+	}
+	if fc.SendQueueSize != nil {
+		conf.SendQueueSize = *fc.SendQueueSize
+	}
+	if fc.WriteBufferSize != nil {
+		conf.WriteBufferSize = *fc.WriteBufferSize
+	}
+	if fc.LogLevel != nil {
+		conf.LogLevel = *fc.LogLevel
+	}
+	if fc.LogFile != nil {
+		conf.LogFile = *fc.LogFile
+	}
+
+	// 工作池配置可以整体省略，但填写时各字段必须完整
+	if wp := fc.WorkerPool; wp != nil {
+		if wp.CoreWorkers == nil {
+			missing = append(missing, "WorkerPool.CoreWorkers")
+		} else {
+			conf.WorkerPool.CoreWorkers = *wp.CoreWorkers
+		}
+		if wp.MaxWorkers == nil {
+			missing = append(missing, "WorkerPool.MaxWorkers")
+		} else {
+			conf.WorkerPool.MaxWorkers = *wp.MaxWorkers
+		}
+		if wp.QueueSize == nil {
+			missing = append(missing, "WorkerPool.QueueSize")
+		} else {
+			conf.WorkerPool.QueueSize = *wp.QueueSize
+		}
+		if wp.IdleTimeout == nil {
+			missing = append(missing, "WorkerPool.IdleTimeout")
+		} else {
+			conf.WorkerPool.IdleTimeout = *wp.IdleTimeout
+		}
+		// 调度模式可选
+		if wp.Mode != nil {
+			conf.WorkerPool.Mode = *wp.Mode
+		}
+		if wp.OverflowPolicy != nil {
+			conf.WorkerPool.OverflowPolicy = *wp.OverflowPolicy
+		}
+		if wp.HighQueueSize != nil {
+			conf.WorkerPool.HighQueueSize = *wp.HighQueueSize
+		}
+		if wp.LowQueueSize != nil {
+			conf.WorkerPool.LowQueueSize = *wp.LowQueueSize
+		}
+		if wp.StarvationLimit != nil {
+			conf.WorkerPool.StarvationLimit = *wp.StarvationLimit
+		}
+	}
+
+	if fc.TLS != nil {
+		conf.TLS = *fc.TLS
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Validate 校验配置参数是否合法
+func (g *GlobalObj) Validate() error {
+	if g.Host == "" {
+		return errors.New("Host must not be empty")
+	}
+	if g.TCPPort < 1 || g.TCPPort > 65535 {
+		return fmt.Errorf("TCPPort must be in [1, 65535], got %d", g.TCPPort)
+	}
+	if g.MaxConn <= 0 {
+		return fmt.Errorf("MaxConn must be positive, got %d", g.MaxConn)
+	}
+	if g.MaxPackageSize == 0 {
+		return errors.New("MaxPackageSize must be positive")
+	}
+	// 发送队列长度为0时 TrySendMsg 总是失败，心跳和拒绝回复都无法发送
+	if g.SendQueueSize == 0 {
+		return errors.New("SendQueueSize must be positive")
+	}
+	if g.LogLevel < DEBUG || g.LogLevel > FATAL {
+		return fmt.Errorf("LogLevel must be in [%d, %d], got %d", DEBUG, FATAL, g.LogLevel)
+	}
+	if err := g.WorkerPool.Validate(); err != nil {
+		return err
+	}
+
+	return g.TLS.Validate()
+}
+
+// Validate 校验工作池配置是否合法
+func (wp *WorkerPoolConfig) Validate() error {
+	if wp.CoreWorkers == 0 {
+		return errors.New("WorkerPool.CoreWorkers must be positive")
+	}
+	if wp.MaxWorkers < wp.CoreWorkers {
+		return fmt.Errorf("WorkerPool.MaxWorkers (%d) must be >= WorkerPool.CoreWorkers (%d)", wp.MaxWorkers, wp.CoreWorkers)
+	}
+	if wp.QueueSize == 0 {
+		return errors.New("WorkerPool.QueueSize must be positive")
+	}
+	if wp.IdleTimeout == 0 {
+		return errors.New("WorkerPool.IdleTimeout must be positive")
+	}
+	if wp.Mode != "" && wp.Mode != DispatchElastic && wp.Mode != DispatchOrdered {
+		return fmt.Errorf("WorkerPool.Mode must be %q or %q, got %q", DispatchElastic, DispatchOrdered, wp.Mode)
+	}
+	if wp.Mode == DispatchOrdered && (wp.HighQueueSize != 0 || wp.LowQueueSize != 0 || wp.StarvationLimit != 0) {
+		return errors.New("WorkerPool priorities (HighQueueSize, LowQueueSize, StarvationLimit) are not supported in ordered mode")
+	}
+	switch wp.OverflowPolicy {
+	case "", OverflowBlock, OverflowDropOldest, OverflowDropNewest, OverflowClose:
+	default:
+		return fmt.Errorf("WorkerPool.OverflowPolicy must be one of %q, %q, %q, %q, got %q",
+			OverflowBlock, OverflowDropOldest, OverflowDropNewest, OverflowClose, wp.OverflowPolicy)
+	}
+	return nil
+}
+
+// Validate 校验TLS配置是否合法
+func (t *TLSConfig) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("TLS.CertFile and TLS.KeyFile must be set together")
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		return errors.New("TLS.ClientCAFile requires TLS.CertFile")
+	}
+	if t.RequireClientCert && t.ClientCAFile == "" {
+		return errors.New("TLS.RequireClientCert requires TLS.ClientCAFile")
+	}
+	return nil
+}
+
+// applyLogger 根据当前配置重新初始化全局日志
+func (g *GlobalObj) applyLogger() {
+	old := GlobalLogger
+
+	if g.LogFile != "" {
+		// 如果配置了日志路径，则使用文件日志，默认文件大小10MB，最多10个文件
+		GlobalLogger = NewLogger(g.LogLevel, g.LogFile, 10*1024*1024, 10)
+	} else {
+		// 否则使用控制台日志
+		GlobalLogger = NewLogger(g.LogLevel, "", 0, 0)
+	}
+
+	if old != nil {
+		old.Close()
+	}
+}
+
+// configPath 获取包初始化时加载的配置文件路径
+// 环境变量指定的配置文件必须存在；默认路径的配置文件不存在时返回false，使用默认配置
+func configPath() (string, bool) {
+	if path := os.Getenv(ConfigEnvKey); path != "" {
+		return path, true
+	}
+	if _, err := os.Stat(DefaultConfigPath); errors.Is(err, os.ErrNotExist) {
+		return "", false
+	}
+	return DefaultConfigPath, true
 }
 
 func init() {
@@ -46,27 +382,36 @@ func init() {
 		Version:        "Latest",
 		MaxConn:        1000,
 		MaxPackageSize: 1024,
+		// 发送队列默认配置
+		SendQueueSize:   64,
+		WriteBufferSize: 4096,
 		// 日志默认配置
 		LogLevel: INFO,
 		LogFile:  "",
 		// 工作池默认配置
 		WorkerPool: WorkerPoolConfig{
-			CoreWorkers: 4,
-			MaxWorkers: 16,
-			QueueSize: 1000,
-			IdleTimeout: 30,
+			CoreWorkers:    4,
+			MaxWorkers:     16,
+			QueueSize:      1000,
+			IdleTimeout:    30,
+			Mode:           DispatchElastic,
+			OverflowPolicy: OverflowDropNewest,
 		},
+		ConfFilePath: DefaultConfigPath,
 	}
 
-	// 尝试从JSON读取配置
-	GlobalObject.Reload()
+	path, ok := configPath()
+	if !ok {
+		GlobalObject.applyLogger()
+		return
+	}
+	GlobalObject.ConfFilePath = path
 
-	// 初始化日志
-	if GlobalObject.LogFile != "" {
-		// 如果配置了日志路径，则使用文件日志，默认文件大小10MB，最多10个文件
-		GlobalLogger = NewLogger(GlobalObject.LogLevel, GlobalObject.LogFile, 10*1024*1024, 10)
-	} else {
-		// 否则使用控制台日志
-		GlobalLogger = NewLogger(GlobalObject.LogLevel, "", 0, 0)
+	// 尝试从JSON读取配置，失败时保留默认配置并记录错误，由 NewServer 创建的服务器在 Start 时返回
+	// 不在这里panic，避免只使用客户端等的程序在加载包时崩溃
+	if err := GlobalObject.Reload(); err != nil {
+		GlobalObject.loadErr = fmt.Errorf("load zinx config failed: %w", err)
+		GlobalObject.applyLogger()
+		GlobalLogger.Error("%v", GlobalObject.loadErr)
 	}
 }
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig 将配置写入临时文件，返回文件路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestConfig 创建使用默认值的配置，不影响 GlobalObject
+func newTestConfig() *GlobalObj {
+	conf := *GlobalObject
+	conf.ConfFilePath = ""
+	conf.loadErr = nil
+	return &conf
+}
+
+func TestLoadConfig(t *testing.T) {
+	conf := newTestConfig()
+	path := writeConfig(t, `{
+		"Host": "0.0.0.0",
+		"TCPPort": 9999,
+		"MaxConn": 10,
+		"MaxPackageSize": 4096,
+		"SendQueueSize": 8,
+		"WorkerPool": {"CoreWorkers": 2, "MaxWorkers": 4, "QueueSize": 100, "IdleTimeout": 10, "Mode": "ordered"}
+	}`)
+
+	if err := conf.LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	if conf.Host != "0.0.0.0" || conf.TCPPort != 9999 || conf.MaxConn != 10 ||
+		conf.MaxPackageSize != 4096 || conf.SendQueueSize != 8 {
+		t.Fatalf("config not loaded: %+v", conf)
+	}
+	if conf.WorkerPool.Mode != DispatchOrdered || conf.WorkerPool.MaxWorkers != 4 {
+		t.Fatalf("WorkerPool not loaded: %+v", conf.WorkerPool)
+	}
+	// 未填写的可选字段保留默认值
+	if conf.Name != GlobalObject.Name || conf.WriteBufferSize != GlobalObject.WriteBufferSize {
+		t.Fatalf("optional fields not kept: Name = %q, WriteBufferSize = %d", conf.Name, conf.WriteBufferSize)
+	}
+	if conf.ConfFilePath != path || conf.LoadErr() != nil {
+		t.Fatalf("ConfFilePath = %q, LoadErr = %v", conf.ConfFilePath, conf.LoadErr())
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	const required = `"Host": "127.0.0.1", "TCPPort": 8888, "MaxConn": 10, "MaxPackageSize": 1024`
+	const workerPool = `"CoreWorkers": 2, "MaxWorkers": 4, "QueueSize": 100, "IdleTimeout": 10`
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"malformed", `{"Host": `, "parse config file"},
+		{"unknown field", `{` + required + `, "MaxConnections": 10}`, "unknown field"},
+		{"missing required", `{"Host": "127.0.0.1", "TCPPort": 8888}`, "MaxConn, MaxPackageSize"},
+		{"missing worker pool field", `{` + required + `, "WorkerPool": {"CoreWorkers": 2}}`, "WorkerPool.MaxWorkers"},
+		{"negative size", `{` + required + `, "SendQueueSize": -1}`, "parse config file"},
+		{"port out of range", `{"Host": "127.0.0.1", "TCPPort": 70000, "MaxConn": 10, "MaxPackageSize": 1024}`, "TCPPort"},
+		{"zero max conn", `{"Host": "127.0.0.1", "TCPPort": 8888, "MaxConn": 0, "MaxPackageSize": 1024}`, "MaxConn"},
+		{"zero max package size", `{"Host": "127.0.0.1", "TCPPort": 8888, "MaxConn": 10, "MaxPackageSize": 0}`, "MaxPackageSize"},
+		{"zero send queue", `{` + required + `, "SendQueueSize": 0}`, "SendQueueSize"},
+		{"max workers below core", `{` + required + `, "WorkerPool": {"CoreWorkers": 8, "MaxWorkers": 4, "QueueSize": 100, "IdleTimeout": 10}}`, "MaxWorkers"},
+		{"bad mode", `{` + required + `, "WorkerPool": {` + workerPool + `, "Mode": "fifo"}}`, "WorkerPool.Mode"},
+		{"bad overflow policy", `{` + required + `, "WorkerPool": {` + workerPool + `, "OverflowPolicy": "retry"}}`, "OverflowPolicy"},
+		{"ordered with priorities", `{` + required + `, "WorkerPool": {` + workerPool + `, "Mode": "ordered", "StarvationLimit": 4}}`, "ordered mode"},
+		{"TLS key without cert", `{` + required + `, "TLS": {"KeyFile": "server.key"}}`, "TLS.CertFile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := newTestConfig()
+			before := *conf
+
+			err := conf.LoadConfig(writeConfig(t, tt.content))
+			if err == nil {
+				t.Fatal("LoadConfig succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadConfig error = %v, want it to contain %q", err, tt.want)
+			}
+			// 加载失败时保留原来的配置
+			if conf.Host != before.Host || conf.MaxConn != before.MaxConn ||
+				conf.SendQueueSize != before.SendQueueSize || conf.ConfFilePath != before.ConfFilePath {
+				t.Fatalf("config changed after failed load: %+v", conf)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	conf := newTestConfig()
+	if err := conf.LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("LoadConfig of missing file succeeded")
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	path := writeConfig(t, `{}`)
+	t.Setenv(ConfigEnvKey, path)
+	if got, ok := configPath(); !ok || got != path {
+		t.Fatalf("configPath() = %q, %v, want %q from %s", got, ok, path, ConfigEnvKey)
+	}
+
+	// 未设置环境变量时使用默认路径，不存在时使用默认配置
+	t.Setenv(ConfigEnvKey, "")
+	t.Chdir(t.TempDir())
+	if got, ok := configPath(); ok {
+		t.Fatalf("configPath() = %q without %s and %s", got, ConfigEnvKey, DefaultConfigPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(DefaultConfigPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(DefaultConfigPath, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := configPath(); !ok || got != DefaultConfigPath {
+		t.Fatalf("configPath() = %q, %v, want %q", got, ok, DefaultConfigPath)
+	}
+}
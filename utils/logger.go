@@ -49,6 +49,11 @@ func NewLogger(level int, file string, maxSize int64, maxFiles int) *Logger {
 		fileExt = filepath.Ext(fileName)
 		fileName = fileName[:len(fileName)-len(fileExt)]
 
+		// 确保日志目录存在
+		if err := os.MkdirAll(filePath, 0755); err != nil {
+			fmt.Printf("Create log dir %s error: %v\n", filePath, err)
+		}
+
 		// 如果文件存在，获取文件信息以确定当前大小和索引
 		if _, err := os.Stat(file); err == nil {
 			// 文件存在，检查是否需要轮换
@@ -225,5 +230,8 @@ func init() {
 	// 如果要启用文件日志，可修改这里的参数
 	// 参数说明：日志级别，日志文件路径，单个文件最大大小（字节），最大文件数量
 	// GlobalLogger = NewLogger(INFO, "logs/zinx.log", 10*1024*1024, 5) // 示例：10MB per file, max 5 files
-	GlobalLogger = NewLogger(INFO, "", 0, 0) // 默认输出到控制台
+	// globalobj.go 的 init 会根据配置初始化日志，这里只在未初始化时兜底
+	if GlobalLogger == nil {
+		GlobalLogger = NewLogger(INFO, "", 0, 0) // 默认输出到控制台
+	}
 }
@@ -24,15 +24,62 @@ type Metrics struct {
 	MessageHandlingCount     uint64        // 消息处理计数
 
 	// 错误相关指标
-	ErrorsTotal uint64 // 总错误数
+	ErrorsTotal      uint64 // 总错误数
+	PanicsTotal      uint64 // 处理器panic数
+	UnknownMsgsTotal uint64 // 未注册msgId的消息数
+
+	// 调用相关指标
+	PendingCalls     int64  // 等待响应的调用数
+	DroppedResponses uint64 // 没有对应调用（如已超时）而被丢弃的响应数
+
+	// 按msgId统计的处理时间
+	MsgHandlingStats map[uint32]*HandlingStat
+
+	// 工作池拒绝的请求数
+	RejectedTotal uint64
+	// 拒绝请求时因发送队列已满而没有回复的次数
+	RejectReplySkipped uint64
+	// 按msgId统计工作池拒绝的请求数
+	RejectedByMsgId map[uint32]uint64
+	// 工作池各优先级队列分发的请求数
+	LaneDispatched map[string]uint64
+}
+
+// HandlingStat 单个msgId的处理时间统计
+type HandlingStat struct {
+	Count     uint64
+	TotalTime time.Duration
+	MaxTime   time.Duration
+}
+
+// Average 平均处理时间
+func (s HandlingStat) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Count)
 }
 
 // 全局性能指标收集器
 var GlobalMetrics *Metrics
 
+// NewMetrics 创建性能指标收集器，可以通过 znet.WithMetrics 交给单个服务器使用
+func NewMetrics() *Metrics {
+	return &Metrics{
+		MsgHandlingStats: make(map[uint32]*HandlingStat),
+		RejectedByMsgId:  make(map[uint32]uint64),
+		LaneDispatched:   make(map[string]uint64),
+	}
+}
+
 // InitMetrics 初始化性能指标收集器
 func InitMetrics() {
-	GlobalMetrics = &Metrics{}
+	GlobalMetrics = NewMetrics()
+}
+
+// 保证在没有创建Server时（如只使用客户端）也可以记录指标
+func init() {
+	InitMetrics()
 }
 
 // IncrementConnectionsTotal 增加总连接数
@@ -75,6 +122,61 @@ func (m *Metrics) RecordMessageHandlingTime(duration time.Duration) {
 	m.MessageHandlingCount++
 }
 
+// RecordMsgHandlingTime 按msgId记录处理时间
+func (m *Metrics) RecordMsgHandlingTime(msgId uint32, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stat, ok := m.MsgHandlingStats[msgId]
+	if !ok {
+		stat = &HandlingStat{}
+		m.MsgHandlingStats[msgId] = stat
+	}
+	stat.Count++
+	stat.TotalTime += duration
+	if duration > stat.MaxTime {
+		stat.MaxTime = duration
+	}
+}
+
+// GetMsgHandlingStat 获取指定msgId的处理时间统计
+func (m *Metrics) GetMsgHandlingStat(msgId uint32) HandlingStat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if stat, ok := m.MsgHandlingStats[msgId]; ok {
+		return *stat
+	}
+	return HandlingStat{}
+}
+
+// IncrementRejected 增加工作池拒绝的请求数
+func (m *Metrics) IncrementRejected(msgId uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RejectedTotal++
+	m.RejectedByMsgId[msgId]++
+}
+
+// GetRejected 获取指定msgId被工作池拒绝的请求数
+func (m *Metrics) GetRejected(msgId uint32) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.RejectedByMsgId[msgId]
+}
+
+// IncrementLaneDispatched 增加工作池指定优先级队列分发的请求数
+func (m *Metrics) IncrementLaneDispatched(lane string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LaneDispatched[lane]++
+}
+
+// GetLaneDispatched 获取工作池指定优先级队列分发的请求数
+func (m *Metrics) GetLaneDispatched(lane string) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.LaneDispatched[lane]
+}
+
 // IncrementErrors 增加错误数
 func (m *Metrics) IncrementErrors() {
 	m.mu.Lock()
@@ -82,6 +184,56 @@ func (m *Metrics) IncrementErrors() {
 	m.ErrorsTotal++
 }
 
+// IncrementPendingCalls 增加等待响应的调用数
+func (m *Metrics) IncrementPendingCalls() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PendingCalls++
+}
+
+// DecrementPendingCalls 减少等待响应的调用数
+func (m *Metrics) DecrementPendingCalls() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PendingCalls--
+}
+
+// GetPendingCalls 获取等待响应的调用数
+func (m *Metrics) GetPendingCalls() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.PendingCalls
+}
+
+// IncrementPanics 增加处理器panic数，同时计入错误数
+func (m *Metrics) IncrementPanics() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PanicsTotal++
+	m.ErrorsTotal++
+}
+
+// IncrementUnknownMsgs 增加未注册msgId的消息数
+func (m *Metrics) IncrementUnknownMsgs() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.UnknownMsgsTotal++
+}
+
+// IncrementRejectReplySkipped 增加拒绝请求时没有回复的次数
+func (m *Metrics) IncrementRejectReplySkipped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RejectReplySkipped++
+}
+
+// IncrementDroppedResponses 增加被丢弃的响应数
+func (m *Metrics) IncrementDroppedResponses() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.DroppedResponses++
+}
+
 // GetAverageMessageHandlingTime 获取平均消息处理时间
 func (m *Metrics) GetAverageMessageHandlingTime() time.Duration {
 	m.mu.RLock()
@@ -112,6 +264,15 @@ Processing:
   Average Time: %v
 Errors:
   Total:       %d
+  Panics:      %d
+  Unknown Msg: %d
+Calls:
+  Pending:     %d
+  Dropped:     %d
+WorkerPool:
+  Rejected:    %d
+  No Reply:    %d
+  Dispatched:  high=%d normal=%d low=%d
 -----------------------------------
 `,
 		m.ConnectionsTotal,
@@ -122,5 +283,14 @@ Errors:
 		m.MessageHandlingTimeTotal,
 		m.GetAverageMessageHandlingTime(),
 		m.ErrorsTotal,
+		m.PanicsTotal,
+		m.UnknownMsgsTotal,
+		m.PendingCalls,
+		m.DroppedResponses,
+		m.RejectedTotal,
+		m.RejectReplySkipped,
+		m.LaneDispatched["high"],
+		m.LaneDispatched["normal"],
+		m.LaneDispatched["low"],
 	)
 }
@@ -0,0 +1,357 @@
+package zclient
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"Go_Zinx/znet"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotConnected 客户端未连接或连接已断开
+var ErrNotConnected = errors.New("zclient: not connected")
+
+// Client IClient的接口实现，与服务端使用相同的路由模型
+type Client struct {
+	Name      string
+	IPVersion string
+	IP        string
+	Port      int
+
+	// 当前的Client注册的Router
+	msgRouter zinterface.IMsgRouter
+
+	// 封包方式，默认为 utils.NewDataPackUtil()
+	dataPack zinterface.IDataPack
+	// 读取的单个消息体的最大长度，默认为 utils.GlobalObject.MaxPackageSize
+	maxPackageSize uint32
+
+	// hook
+	OnConnect    func(conn zinterface.IConnection)
+	OnDisconnect func(conn zinterface.IConnection)
+
+	// 心跳协议，为nil时关闭心跳
+	heartbeat *zinterface.HeartbeatConfig
+
+	// TLS配置，为nil时不使用TLS
+	tlsConfig *tls.Config
+
+	// 当前连接
+	conn     *Connection
+	connLock sync.RWMutex
+	// 连接ID
+	nextConnID uint32
+
+	// 断线重连配置，为nil时不重连
+	reconnect *ReconnectConfig
+	// 重连相关的回调
+	OnReconnectAttempt func(attempt int, delay time.Duration)
+	OnReconnected      func(conn zinterface.IConnection, attempts int)
+	OnReconnectGiveUp  func(attempts int, lastErr error)
+
+	// 断线期间缓存的消息
+	offlineQueue []offlineMsg
+	offlineLock  sync.Mutex
+	// 是否正在重连
+	reconnecting atomic.Bool
+
+	// 客户端是否已被主动停止
+	stopped  atomic.Bool
+	stopChan chan struct{}
+	stopOnce *sync.Once
+}
+
+// NewClient 创建一个客户端，默认开启心跳
+func NewClient(ip string, port int) *Client {
+	c := &Client{
+		Name:      "zinx client",
+		IPVersion: "tcp",
+		IP:        ip,
+		Port:      port,
+		msgRouter: znet.NewMsgRouter(),
+		dataPack:  utils.NewDataPackUtil(),
+		heartbeat: znet.DefaultHeartbeatConfig(),
+	}
+	c.maxPackageSize = utils.GlobalObject.MaxPackageSize
+
+	return c
+}
+
+func (c *Client) SetOnConnect(f func(connection zinterface.IConnection)) {
+	c.OnConnect = f
+}
+
+func (c *Client) SetOnDisconnect(f func(connection zinterface.IConnection)) {
+	c.OnDisconnect = f
+}
+
+// Client添加一个Handler
+func (c *Client) AddHandler(msgId uint32, handler zinterface.IHandler) {
+	c.msgRouter.AddHandler(msgId, handler)
+}
+
+// AddHandlerFunc 使用函数注册处理器
+func (c *Client) AddHandlerFunc(msgId uint32, f zinterface.HandlerFunc) {
+	c.msgRouter.AddHandlerFunc(msgId, f)
+}
+
+// SetCodec 设置默认的消息体编解码器
+func (c *Client) SetCodec(codec zinterface.ICodec) {
+	c.msgRouter.SetCodec(codec)
+}
+
+// SetMsgCodec 设置指定msgId的消息体编解码器
+func (c *Client) SetMsgCodec(msgId uint32, codec zinterface.ICodec) {
+	c.msgRouter.SetMsgCodec(msgId, codec)
+}
+
+// GetMsgRouter 获取客户端的路由
+func (c *Client) GetMsgRouter() zinterface.IMsgRouter {
+	return c.msgRouter
+}
+
+// SetDataPack 设置客户端使用的封包方式，需要在 Start 之前调用
+func (c *Client) SetDataPack(dp zinterface.IDataPack) {
+	c.dataPack = dp
+}
+
+// GetDataPack 获取客户端使用的封包方式
+func (c *Client) GetDataPack() zinterface.IDataPack {
+	return c.dataPack
+}
+
+// SetMaxPackageSize 设置读取的单个消息体的最大长度，为0时不限制，需要在 Start 之前调用
+// 服务端推送的消息超过限制时关闭连接，默认使用配置文件中的 MaxPackageSize
+func (c *Client) SetMaxPackageSize(size uint32) {
+	c.maxPackageSize = size
+}
+
+// SetTLSConfig 使用TLS连接服务器，为nil时不使用TLS，需要在 Start 之前调用
+// 服务端证书验证通过后，其信息放入连接属性（见 znet.PropertyPeerCommonName）
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// SetHeartbeat 设置心跳间隔和超时时间，interval 为0时关闭心跳
+// 超时时间换算为 MaxMissed，其余参数保持当前心跳协议的设置
+func (c *Client) SetHeartbeat(interval, timeout time.Duration) {
+	if interval <= 0 {
+		c.heartbeat = nil
+		return
+	}
+	if c.heartbeat == nil {
+		c.heartbeat = znet.DefaultHeartbeatConfig()
+	}
+	c.heartbeat.Interval = interval
+	c.heartbeat.MaxMissed = int((timeout + interval - 1) / interval)
+}
+
+// SetHeartbeatConfig 设置心跳协议，为nil时关闭心跳，需要在 Start 之前调用
+// MsgId 和 Direction 需要与服务端一致
+func (c *Client) SetHeartbeatConfig(cfg *zinterface.HeartbeatConfig) {
+	c.heartbeat = cfg
+}
+
+// GetHeartbeatConfig 获取心跳协议，未开启时返回nil
+func (c *Client) GetHeartbeatConfig() *zinterface.HeartbeatConfig {
+	return c.heartbeat
+}
+
+// Start 连接服务器并启动读写协程
+func (c *Client) Start() error {
+	c.stopped.Store(false)
+	c.stopChan = make(chan struct{})
+	c.stopOnce = &sync.Once{}
+
+	dealConn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.activate(dealConn)
+	return nil
+}
+
+// dial 建立一个新的连接，但不启动读写协程
+func (c *Client) dial() (*Connection, error) {
+	addr := net.JoinHostPort(c.IP, strconv.Itoa(c.Port))
+	var conn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		// 握手在 Dial 中完成，证书验证失败时返回错误
+		dialer := &tls.Dialer{Config: c.tlsConfig}
+		conn, err = dialer.Dial(c.IPVersion, addr)
+	} else {
+		conn, err = net.Dial(c.IPVersion, addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial %s error: %w", addr, err)
+	}
+
+	c.connLock.Lock()
+	c.nextConnID++
+	connID := c.nextConnID
+	c.connLock.Unlock()
+
+	utils.GlobalLogger.Info("[Start] Client %s connected to %s", c.Name, addr)
+	dealConn := newConnection(c, conn, connID)
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		znet.SetPeerIdentity(dealConn, tlsConn.ConnectionState())
+	}
+	return dealConn, nil
+}
+
+// activate 启动连接，发送断线期间缓存的消息后将其设为当前连接
+func (c *Client) activate(dealConn *Connection) {
+	dealConn.Start()
+	if cfg := c.heartbeat; cfg != nil && cfg.Interval > 0 {
+		go c.startHeartbeat(dealConn, cfg)
+	}
+
+	c.offlineLock.Lock()
+	// 先发送缓存的消息，保证它们在新消息之前发出
+	for _, msg := range c.offlineQueue {
+		if err := dealConn.SendMsg(msg.msgId, msg.data); err != nil {
+			utils.GlobalLogger.Warn("Client resend offline msg %d error: %v", msg.msgId, err)
+		}
+	}
+	c.offlineQueue = nil
+
+	c.connLock.Lock()
+	c.conn = dealConn
+	c.connLock.Unlock()
+	c.offlineLock.Unlock()
+
+	// 重连完成，在回调之前清除重连状态，保证新连接断开时可以再次重连
+	// 新连接在此之前已经断开时，onConnClosed 无法开始重连，在这里补上
+	if c.reconnecting.CompareAndSwap(true, false) && !dealConn.IsAlive() {
+		c.reconnectClosed(dealConn)
+	}
+
+	if c.OnConnect != nil {
+		c.OnConnect(dealConn)
+	}
+}
+
+// Stop 断开连接，不再重连
+func (c *Client) Stop() {
+	c.stopped.Store(true)
+	if c.stopOnce != nil {
+		c.stopOnce.Do(func() {
+			close(c.stopChan)
+		})
+	}
+
+	if conn := c.getConn(); conn != nil {
+		conn.Stop()
+	}
+	c.dropOfflineQueue()
+}
+
+// GetConnection 获取当前的连接，未连接时返回nil
+func (c *Client) GetConnection() zinterface.IConnection {
+	if conn := c.getConn(); conn != nil {
+		return conn
+	}
+	return nil
+}
+
+// SendMsg 通过当前连接发送数据
+// 断线重连期间根据 ReconnectConfig.OfflinePolicy 缓存或拒绝消息
+func (c *Client) SendMsg(msgId uint32, data []byte) error {
+	conn := c.getConn()
+	if conn == nil {
+		return c.sendOffline(msgId, data)
+	}
+	return conn.SendMsg(msgId, data)
+}
+
+// SendObject 使用该msgId的编解码器编码v后发送
+func (c *Client) SendObject(msgId uint32, v any) error {
+	data, err := c.msgRouter.GetCodec(msgId).Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.SendMsg(msgId, data)
+}
+
+// Call 通过当前连接发送请求并等待响应
+func (c *Client) Call(ctx context.Context, msgId uint32, data []byte) ([]byte, error) {
+	conn := c.getConn()
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	return conn.Call(ctx, msgId, data)
+}
+
+func (c *Client) getConn() *Connection {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.conn
+}
+
+// onConnClosed 连接关闭时回调
+func (c *Client) onConnClosed(conn *Connection) {
+	// 不是主动停止的，尝试重连；先标记重连状态，保证断线期间发送的消息能被缓存
+	willReconnect := c.reconnect != nil && !c.stopped.Load() && c.reconnecting.CompareAndSwap(false, true)
+
+	c.connLock.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.connLock.Unlock()
+
+	if c.OnDisconnect != nil {
+		c.OnDisconnect(conn)
+	}
+
+	if willReconnect {
+		go c.reconnectLoop(conn)
+	}
+}
+
+// reconnectClosed 为已经断开的连接开始重连
+func (c *Client) reconnectClosed(conn *Connection) {
+	if c.reconnect == nil || c.stopped.Load() || !c.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+
+	c.connLock.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.connLock.Unlock()
+
+	go c.reconnectLoop(conn)
+}
+
+// startHeartbeat 每个心跳间隔检查一次服务端是否失效，需要时发送ping
+func (c *Client) startHeartbeat(conn *Connection, cfg *zinterface.HeartbeatConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	pings := znet.HeartbeatPings(cfg, false)
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(conn.lastRecv()) >= znet.HeartbeatTimeout(cfg) {
+				// 回调没有关闭连接时，下一个间隔会再次检查
+				znet.RemoteNotAlive(cfg, conn)
+				continue
+			}
+
+			if pings {
+				// 发送队列已满说明连接仍在发送数据，跳过本次ping
+				conn.TrySendMsg(cfg.MsgId, znet.HeartbeatPing(cfg, conn))
+			}
+		case <-conn.ExitChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package zclient
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"Go_Zinx/znet"
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Connection 客户端侧的连接，实现zinterface.IConnection
+// 与服务端的Connection一样使用一对读写协程，收到的消息交给路由处理
+type Connection struct {
+	// 隶属Client
+	client *Client
+
+	Conn net.Conn
+
+	// 客户端连接的ID，每次建立连接递增
+	ConnID uint32
+
+	isClosed atomic.Bool
+
+	// 去告知链接已退出的channel
+	ExitChan chan struct{}
+
+	// 连接的context，连接关闭时被取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 发送队列，写协程从这里取数据
+	MsgChan chan []byte
+
+	// 待处理请求的管道，保证按接收顺序处理
+	reqChan chan zinterface.IRequest
+
+	// 该链接处理的方法Router
+	Router zinterface.IMsgRouter
+
+	// 该链接使用的封包方式
+	dataPack zinterface.IDataPack
+	// 读取的单个消息体的最大长度，为0时不限制
+	maxPackageSize uint32
+
+	// 等待对端响应的调用
+	calls *znet.PendingCalls
+
+	// 最后一次收到数据的时间（UnixNano）
+	lastRecvTime atomic.Int64
+
+	properties     map[string]any
+	propertiesLock sync.RWMutex
+
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newConnection(client *Client, conn net.Conn, connID uint32) *Connection {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Connection{
+		client:     client,
+		Conn:       conn,
+		ConnID:     connID,
+		ExitChan:   make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		MsgChan:    make(chan []byte, utils.GlobalObject.SendQueueSize),
+		reqChan:    make(chan zinterface.IRequest, 64),
+		Router:     client.msgRouter,
+		dataPack:   client.dataPack,
+		calls:      znet.NewPendingCalls(),
+		properties: make(map[string]any),
+	}
+	c.maxPackageSize = client.maxPackageSize
+	c.lastRecvTime.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *Connection) SetProperty(key string, value any) {
+	c.propertiesLock.Lock()
+	defer c.propertiesLock.Unlock()
+	c.properties[key] = value
+}
+
+func (c *Connection) GetProperty(key string) (any, error) {
+	c.propertiesLock.RLock()
+	defer c.propertiesLock.RUnlock()
+	if value, ok := c.properties[key]; ok {
+		return value, nil
+	} else {
+		return nil, errors.New("No Property Found!")
+	}
+}
+
+func (c *Connection) RemoveProperty(key string) {
+	c.propertiesLock.Lock()
+	defer c.propertiesLock.Unlock()
+	delete(c.properties, key)
+}
+
+// copyPropertiesTo 将属性复制到另一个连接
+// 对端身份属于连接本身，由新连接的握手设置，不复制
+func (c *Connection) copyPropertiesTo(dst *Connection) {
+	c.propertiesLock.RLock()
+	defer c.propertiesLock.RUnlock()
+	for key, value := range c.properties {
+		if znet.IsPeerProperty(key) {
+			continue
+		}
+		dst.SetProperty(key, value)
+	}
+}
+
+// Start 启动读写协程和消息处理协程
+func (c *Connection) Start() {
+	utils.GlobalLogger.Info("Client Conn Start... ConnID = %d", c.ConnID)
+
+	c.wg.Add(3)
+	go c.startReader()
+	go c.startWriter()
+	go c.startDispatcher()
+}
+
+func (c *Connection) startWriter() {
+	defer c.wg.Done()
+	defer utils.GlobalLogger.Info("client connID = %d Writer stopped", c.ConnID)
+
+	writer := bufio.NewWriterSize(c.Conn, int(utils.GlobalObject.WriteBufferSize))
+
+	for {
+		select {
+		case data := <-c.MsgChan:
+			_, err := writer.Write(data)
+			// 队列中没有更多消息时刷新缓冲区，多个消息合并为一次系统调用
+			if err == nil && len(c.MsgChan) == 0 {
+				err = writer.Flush()
+			}
+			if err != nil {
+				utils.GlobalLogger.Errorf("Client send data error: %v", err)
+				go c.Stop()
+				return
+			}
+		case <-c.ExitChan:
+			return
+		}
+	}
+}
+
+func (c *Connection) startReader() {
+	defer c.wg.Done()
+	defer utils.GlobalLogger.Info("client connID = %d Reader stopped", c.ConnID)
+	defer func() { go c.Stop() }()
+
+	heartbeat := c.client.heartbeat
+	for {
+		// utils.ReadMessageLimit 使用 io.ReadFull，不会出现半包
+		msg, err := utils.ReadMessageLimit(c.Conn, c.dataPack, c.maxPackageSize)
+		if err != nil {
+			if !c.isClosed.Load() {
+				utils.GlobalLogger.Errorf("client read msg error: %v", err)
+			}
+			return
+		}
+		c.lastRecvTime.Store(time.Now().UnixNano())
+
+		// 心跳消息直接处理，不经过路由
+		if heartbeat != nil && msg.GetMsgId() == heartbeat.MsgId {
+			znet.HandleHeartbeat(heartbeat, c, msg, false)
+			continue
+		}
+
+		// 响应帧交给等待的调用或丢弃，不经过路由
+		if c.calls.Deliver(msg) {
+			continue
+		}
+
+		select {
+		case c.reqChan <- znet.NewRequest(c, msg):
+		case <-c.ExitChan:
+			return
+		}
+	}
+}
+
+// startDispatcher 按接收顺序将请求交给路由处理，避免处理器阻塞读协程
+func (c *Connection) startDispatcher() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case req := <-c.reqChan:
+			c.Router.DoMsgHandler(req)
+		case <-c.ExitChan:
+			return
+		}
+	}
+}
+
+// Stop 关闭连接，可以重复调用
+func (c *Connection) Stop() {
+	c.stopOnce.Do(func() {
+		utils.GlobalLogger.Info("Client Conn Stop..., ConnID = %d", c.ConnID)
+
+		c.isClosed.Store(true)
+		c.Conn.Close()
+		close(c.ExitChan)
+		c.cancel()
+		c.calls.Close()
+
+		c.client.onConnClosed(c)
+	})
+}
+
+// IsAlive 连接是否处于活跃状态
+func (c *Connection) IsAlive() bool {
+	return !c.isClosed.Load()
+}
+
+// Done 返回连接关闭时被关闭的channel
+func (c *Connection) Done() <-chan struct{} {
+	return c.ExitChan
+}
+
+// Context 获取连接的context，连接关闭时被取消
+func (c *Connection) Context() context.Context {
+	return c.ctx
+}
+
+// GetConn 获取底层连接
+func (c *Connection) GetConn() net.Conn {
+	return c.Conn
+}
+
+// GetListener 客户端连接没有监听器，返回空字符串
+func (c *Connection) GetListener() string {
+	return ""
+}
+
+// GetTCPConnection 获取底层的TCP连接，非TCP连接时返回nil
+func (c *Connection) GetTCPConnection() *net.TCPConn {
+	tcpConn, _ := c.Conn.(*net.TCPConn)
+	return tcpConn
+}
+
+func (c *Connection) GetConnId() uint32 {
+	return c.ConnID
+}
+
+func (c *Connection) RemoteAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+// SendMsg 发送数据，发送队列已满时阻塞等待，直到有空位或连接关闭
+func (c *Connection) SendMsg(msgId uint32, data []byte) error {
+	return c.SendMessage(znet.NewMsgPackage(msgId, data))
+}
+
+// TrySendMsg 发送数据，发送队列已满时立即返回 znet.ErrSendQueueFull
+func (c *Connection) TrySendMsg(msgId uint32, data []byte) error {
+	return c.sendMessage(znet.NewMsgPackage(msgId, data), -1)
+}
+
+// SendMsgTimeout 发送数据，发送队列已满时最多等待timeout，超时返回 znet.ErrSendQueueFull
+func (c *Connection) SendMsgTimeout(msgId uint32, data []byte, timeout time.Duration) error {
+	return c.sendMessage(znet.NewMsgPackage(msgId, data), timeout)
+}
+
+// SendMessage 发送一个完整的消息，扩展消息头中的字段会一并发送
+func (c *Connection) SendMessage(msg zinterface.IMessage) error {
+	return c.sendMessage(msg, 0)
+}
+
+// TrySendMessage 发送一个完整的消息，发送队列已满时立即返回 znet.ErrSendQueueFull
+func (c *Connection) TrySendMessage(msg zinterface.IMessage) error {
+	return c.sendMessage(msg, -1)
+}
+
+// sendMessage 封包后放入发送队列
+// 队列已满时，timeout 小于0不等待，等于0一直等待，大于0最多等待timeout
+func (c *Connection) sendMessage(msg zinterface.IMessage, timeout time.Duration) error {
+	if c.isClosed.Load() {
+		return ErrNotConnected
+	}
+
+	binaryMsg, err := c.dataPack.Pack(msg)
+	if err != nil {
+		utils.GlobalLogger.Errorf("Client pack error msg id = %d", msg.GetMsgId())
+		return errors.New("pack error msg")
+	}
+
+	select {
+	case c.MsgChan <- binaryMsg:
+		return nil
+	default:
+	}
+	if timeout < 0 {
+		return znet.ErrSendQueueFull
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case c.MsgChan <- binaryMsg:
+		return nil
+	case <-expired:
+		return znet.ErrSendQueueFull
+	case <-c.ExitChan:
+		return ErrNotConnected
+	}
+}
+
+// SendObject 使用路由中该msgId的编解码器编码v后发送
+func (c *Connection) SendObject(msgId uint32, v any) error {
+	data, err := c.Router.GetCodec(msgId).Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.SendMsg(msgId, data)
+}
+
+// Call 向服务端发送请求并等待响应
+// 需要使用传输序列号的封包方式，如 utils.NewExtDataPack()
+func (c *Connection) Call(ctx context.Context, msgId uint32, data []byte) ([]byte, error) {
+	return c.calls.Call(ctx, c.dataPack, c.SendMessage, msgId, data)
+}
+
+// GetPendingCalls 获取等待响应的调用数
+func (c *Connection) GetPendingCalls() int {
+	return c.calls.Len()
+}
+
+// GetDataPack 获取连接使用的封包方式
+func (c *Connection) GetDataPack() zinterface.IDataPack {
+	return c.dataPack
+}
+
+// GetRouter 获取连接的路由
+func (c *Connection) GetRouter() zinterface.IMsgRouter {
+	return c.Router
+}
+
+// lastRecv 获取最后一次收到数据的时间
+func (c *Connection) lastRecv() time.Time {
+	return time.Unix(0, c.lastRecvTime.Load())
+}
@@ -0,0 +1,208 @@
+package zclient
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrOfflineQueueFull 断线期间缓存的消息已达上限
+var ErrOfflineQueueFull = errors.New("zclient: offline queue is full")
+
+// OfflinePolicy 断线期间发送消息的处理策略
+type OfflinePolicy int
+
+const (
+	// OfflineReject 直接返回 ErrNotConnected
+	OfflineReject OfflinePolicy = iota
+	// OfflineQueue 缓存消息，重连成功后按顺序发送
+	OfflineQueue
+)
+
+// 默认重连参数
+const (
+	DefaultReconnectInitialDelay = 500 * time.Millisecond
+	DefaultReconnectMaxDelay     = 30 * time.Second
+	DefaultReconnectMultiplier   = 2.0
+	DefaultReconnectJitter       = 0.2
+	DefaultOfflineQueueLimit     = 1024
+)
+
+// ReconnectConfig 断线重连配置
+type ReconnectConfig struct {
+	InitialDelay time.Duration // 第一次重连前的等待时间
+	MaxDelay     time.Duration // 最大等待时间
+	Multiplier   float64       // 每次失败后等待时间的倍数
+	Jitter       float64       // 随机抖动比例，取值[0, 1]
+	MaxAttempts  int           // 最大重连次数，0表示不限制
+
+	OfflinePolicy     OfflinePolicy // 断线期间发送消息的处理策略
+	OfflineQueueLimit int           // 断线期间最多缓存的消息数
+
+	KeepProperties bool // 重连后是否保留旧连接上设置的属性，不包括TLS对端身份
+}
+
+// DefaultReconnectConfig 默认的重连配置：指数退避，不限次数，断线期间拒绝发送
+func DefaultReconnectConfig() *ReconnectConfig {
+	return &ReconnectConfig{
+		InitialDelay:      DefaultReconnectInitialDelay,
+		MaxDelay:          DefaultReconnectMaxDelay,
+		Multiplier:        DefaultReconnectMultiplier,
+		Jitter:            DefaultReconnectJitter,
+		OfflinePolicy:     OfflineReject,
+		OfflineQueueLimit: DefaultOfflineQueueLimit,
+	}
+}
+
+// offlineMsg 断线期间缓存的消息
+type offlineMsg struct {
+	msgId uint32
+	data  []byte
+}
+
+// SetReconnect 开启断线重连，config 为nil时关闭
+func (c *Client) SetReconnect(config *ReconnectConfig) {
+	c.reconnect = config
+}
+
+func (c *Client) SetOnReconnectAttempt(f func(attempt int, delay time.Duration)) {
+	c.OnReconnectAttempt = f
+}
+
+func (c *Client) SetOnReconnected(f func(conn zinterface.IConnection, attempts int)) {
+	c.OnReconnected = f
+}
+
+func (c *Client) SetOnReconnectGiveUp(f func(attempts int, lastErr error)) {
+	c.OnReconnectGiveUp = f
+}
+
+// IsReconnecting 是否正在重连
+func (c *Client) IsReconnecting() bool {
+	return c.reconnecting.Load()
+}
+
+// sendOffline 处理断线期间发送的消息
+func (c *Client) sendOffline(msgId uint32, data []byte) error {
+	cfg := c.reconnect
+	if cfg == nil || cfg.OfflinePolicy != OfflineQueue || !c.reconnecting.Load() {
+		return ErrNotConnected
+	}
+
+	c.offlineLock.Lock()
+	defer c.offlineLock.Unlock()
+
+	// 加锁后再检查一次，重连可能已经完成
+	if conn := c.getConn(); conn != nil {
+		return conn.SendMsg(msgId, data)
+	}
+	if !c.reconnecting.Load() {
+		return ErrNotConnected
+	}
+
+	if cfg.OfflineQueueLimit > 0 && len(c.offlineQueue) >= cfg.OfflineQueueLimit {
+		return ErrOfflineQueueFull
+	}
+	c.offlineQueue = append(c.offlineQueue, offlineMsg{msgId: msgId, data: data})
+	return nil
+}
+
+// dropOfflineQueue 丢弃缓存的消息
+func (c *Client) dropOfflineQueue() {
+	c.offlineLock.Lock()
+	defer c.offlineLock.Unlock()
+	if len(c.offlineQueue) > 0 {
+		utils.GlobalLogger.Warn("Client %s dropped %d offline msgs", c.Name, len(c.offlineQueue))
+	}
+	c.offlineQueue = nil
+}
+
+// reconnectLoop 按指数退避重连，直到成功、达到最大次数或客户端被停止
+func (c *Client) reconnectLoop(oldConn *Connection) {
+	// 重连成功时由 activate 清除重连状态
+	reconnected := false
+	defer func() {
+		if !reconnected {
+			c.reconnecting.Store(false)
+		}
+	}()
+
+	cfg := c.reconnect
+	var lastErr error
+
+	for attempt := 1; cfg.MaxAttempts <= 0 || attempt <= cfg.MaxAttempts; attempt++ {
+		delay := backoffDelay(cfg, attempt)
+		if c.OnReconnectAttempt != nil {
+			c.OnReconnectAttempt(attempt, delay)
+		}
+		utils.GlobalLogger.Info("Client %s reconnect attempt %d after %v", c.Name, attempt, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.stopChan:
+			timer.Stop()
+			c.dropOfflineQueue()
+			return
+		}
+
+		dealConn, err := c.dial()
+		if err != nil {
+			lastErr = err
+			utils.GlobalLogger.Warn("Client %s reconnect attempt %d failed: %v", c.Name, attempt, err)
+			continue
+		}
+
+		if cfg.KeepProperties {
+			oldConn.copyPropertiesTo(dealConn)
+		}
+
+		// Stop 可能在拨号期间被调用
+		if c.stopped.Load() {
+			dealConn.Conn.Close()
+			c.dropOfflineQueue()
+			return
+		}
+
+		reconnected = true
+		c.activate(dealConn)
+		utils.GlobalLogger.Info("Client %s reconnected after %d attempts", c.Name, attempt)
+		if c.OnReconnected != nil {
+			c.OnReconnected(dealConn, attempt)
+		}
+		return
+	}
+
+	utils.GlobalLogger.Error("Client %s gave up reconnecting after %d attempts: %v", c.Name, cfg.MaxAttempts, lastErr)
+	c.dropOfflineQueue()
+	if c.OnReconnectGiveUp != nil {
+		c.OnReconnectGiveUp(cfg.MaxAttempts, lastErr)
+	}
+}
+
+// backoffDelay 计算第attempt次重连前的等待时间
+func backoffDelay(cfg *ReconnectConfig, attempt int) time.Duration {
+	initial := cfg.InitialDelay
+	if initial <= 0 {
+		initial = DefaultReconnectInitialDelay
+	}
+	multiplier := cfg.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if cfg.MaxDelay > 0 && delay > float64(cfg.MaxDelay) {
+		delay = float64(cfg.MaxDelay)
+	}
+
+	if cfg.Jitter > 0 {
+		jitter := math.Min(cfg.Jitter, 1)
+		// 在 [1-jitter, 1+jitter] 范围内随机
+		delay *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,30 @@
+package zcodec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// GobCodec 使用 encoding/gob 编解码消息体，适用于两端都是Go程序的场景
+type GobCodec struct {
+}
+
+func NewGobCodec() *GobCodec {
+	return &GobCodec{}
+}
+
+func (c *GobCodec) Name() string {
+	return "gob"
+}
+
+func (c *GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
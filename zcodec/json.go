@@ -0,0 +1,23 @@
+package zcodec
+
+import "encoding/json"
+
+// JSONCodec 使用 encoding/json 编解码消息体
+type JSONCodec struct {
+}
+
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{}
+}
+
+func (c *JSONCodec) Name() string {
+	return "json"
+}
+
+func (c *JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c *JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
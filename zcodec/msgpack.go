@@ -0,0 +1,23 @@
+package zcodec
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// MsgPackCodec 使用 MessagePack 编解码消息体
+type MsgPackCodec struct {
+}
+
+func NewMsgPackCodec() *MsgPackCodec {
+	return &MsgPackCodec{}
+}
+
+func (c *MsgPackCodec) Name() string {
+	return "msgpack"
+}
+
+func (c *MsgPackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (c *MsgPackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
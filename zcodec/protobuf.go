@@ -0,0 +1,35 @@
+package zcodec
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtobufCodec 使用 Protobuf 编解码消息体，v 必须是生成的 proto.Message
+type ProtobufCodec struct {
+}
+
+func NewProtobufCodec() *ProtobufCodec {
+	return &ProtobufCodec{}
+}
+
+func (c *ProtobufCodec) Name() string {
+	return "protobuf"
+}
+
+func (c *ProtobufCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (c *ProtobufCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
@@ -0,0 +1,62 @@
+package zinterface
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+)
+
+// 定义一个客户端接口
+type IClient interface {
+	// 连接服务器并启动读写协程
+	Start() error
+	// 断开连接
+	Stop()
+
+	// 路由功能：给当前的客户端注册一个路由方法
+	AddHandler(msgId uint32, handler IHandler)
+
+	// 使用函数注册处理器
+	AddHandlerFunc(msgId uint32, f HandlerFunc)
+
+	GetMsgRouter() IMsgRouter
+
+	// 获取当前的连接，未连接时返回nil
+	GetConnection() IConnection
+
+	// 发送数据
+	SendMsg(msgId uint32, data []byte) error
+
+	// 使用该msgId的编解码器编码v后发送
+	SendObject(msgId uint32, v any) error
+
+	// 设置默认的消息体编解码器
+	SetCodec(codec ICodec)
+
+	// 设置指定msgId的消息体编解码器
+	SetMsgCodec(msgId uint32, codec ICodec)
+
+	// 发送请求并等待响应
+	Call(ctx context.Context, msgId uint32, data []byte) ([]byte, error)
+
+	// 设置封包方式，需要在 Start 之前调用
+	SetDataPack(dp IDataPack)
+
+	GetDataPack() IDataPack
+
+	// 设置读取的单个消息体的最大长度，为0时不限制，需要在 Start 之前调用
+	SetMaxPackageSize(size uint32)
+
+	// 设置心跳间隔和超时时间，interval 为0时关闭心跳
+	SetHeartbeat(interval, timeout time.Duration)
+
+	// 使用TLS连接服务器，为nil时不使用TLS，需要在 Start 之前调用
+	SetTLSConfig(config *tls.Config)
+
+	// 设置心跳协议，为nil时关闭心跳，需要在 Start 之前调用
+	SetHeartbeatConfig(cfg *HeartbeatConfig)
+
+	SetOnConnect(func(connection IConnection))
+
+	SetOnDisconnect(func(connection IConnection))
+}
@@ -0,0 +1,11 @@
+package zinterface
+
+// 消息体的编解码接口
+type ICodec interface {
+	// 编解码器名称
+	Name() string
+
+	Marshal(v any) ([]byte, error)
+
+	Unmarshal(data []byte, v any) error
+}
@@ -1,26 +1,66 @@
 package zinterface
 
-import "net"
+import (
+	"context"
+	"net"
+	"time"
+)
 
 type IConnection interface {
 	// 启动连接
 	Start()
 
-	// 停止链接
+	// 停止链接，可以重复调用
 	Stop()
 
-	// 获取绑定的 Socket
+	// 连接是否处于活跃状态
+	IsAlive() bool
+
+	// 连接关闭时被关闭的channel
+	Done() <-chan struct{}
+
+	// 连接的context，连接关闭时被取消
+	Context() context.Context
+
+	// 获取绑定的 TCP Socket，TLS或unix连接返回nil
 	GetTCPConnection() *net.TCPConn
 
+	// 获取底层连接
+	GetConn() net.Conn
+
+	// 获取接受该连接的监听器名称，客户端连接返回空字符串
+	GetListener() string
+
 	// 获取ID
 	GetConnId() uint32
 
 	// 获取 Address
 	RemoteAddr() net.Addr
 
-	// 发送数据
+	// 发送数据，发送队列已满时阻塞等待
 	SendMsg(msgId uint32, data []byte) error
 
+	// 发送数据，发送队列已满时立即返回错误
+	TrySendMsg(msgId uint32, data []byte) error
+
+	// 发送数据，发送队列已满时最多等待timeout
+	SendMsgTimeout(msgId uint32, data []byte, timeout time.Duration) error
+
+	// 发送一个完整的消息，包括扩展消息头中的字段
+	SendMessage(msg IMessage) error
+
+	// 发送一个完整的消息，发送队列已满时立即返回错误
+	TrySendMessage(msg IMessage) error
+
+	// 使用该msgId的编解码器编码v后发送
+	SendObject(msgId uint32, v any) error
+
+	// 发送请求并等待对端通过 IRequest.Reply 回复
+	Call(ctx context.Context, msgId uint32, data []byte) ([]byte, error)
+
+	// 获取等待响应的调用数
+	GetPendingCalls() int
+
 	SetProperty(key string, value any)
 
 	GetProperty(key string) (any, error)
@@ -29,4 +69,7 @@ type IConnection interface {
 
 	// 获取路由
 	GetRouter() IMsgRouter
+
+	// 获取封包方式
+	GetDataPack() IDataPack
 }
@@ -1,9 +1,48 @@
 package zinterface
 
+// DuplicateKeyPolicy 同一个索引值绑定到多个连接时的处理策略
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeyKick 关闭之前绑定的连接，如同一用户重复登录时踢掉旧连接
+	DuplicateKeyKick DuplicateKeyPolicy = iota
+	// DuplicateKeyReject 拒绝新的绑定
+	DuplicateKeyReject
+	// DuplicateKeyAllow 允许多个连接绑定同一个值
+	DuplicateKeyAllow
+)
+
 type IConnManager interface {
 	AddConn(conn IConnection)
 	RemoteConn(connId uint32)
 	GetConn(connId uint32) (IConnection, error)
 	Len() int
 	ClearConn()
+
+	// 遍历所有连接，f返回false时停止遍历
+	Range(f func(conn IConnection) bool)
+
+	// 向所有连接发送消息，返回成功放入发送队列的连接数
+	Broadcast(msgId uint32, data []byte) int
+
+	// 向除exceptConnIds以外的所有连接发送消息，返回成功放入发送队列的连接数
+	BroadcastExcept(msgId uint32, data []byte, exceptConnIds ...uint32) int
+
+	// 向指定的连接发送消息，返回成功放入发送队列的连接数
+	Multicast(connIds []uint32, msgId uint32, data []byte) int
+
+	// 在名为index的索引中将value绑定到连接，连接关闭时自动解绑
+	BindKey(index string, value string, conn IConnection) error
+
+	// 解除value与连接的绑定
+	UnbindKey(index string, value string, conn IConnection)
+
+	// 获取绑定到value的连接，有多个时返回最后绑定的
+	GetByKey(index string, value string) (IConnection, error)
+
+	// 获取绑定到value的所有连接
+	GetAllByKey(index string, value string) []IConnection
+
+	// 设置索引的重复绑定策略，默认为 DuplicateKeyKick
+	SetDuplicateKeyPolicy(index string, policy DuplicateKeyPolicy)
 }
@@ -1,5 +1,7 @@
 package zinterface
 
+import "io"
+
 // 封包、拆包的接口
 type IDataPack interface {
 	GetHeadLen() uint32
@@ -8,3 +10,11 @@ type IDataPack interface {
 
 	Unpack([]byte) (IMessage, error)
 }
+
+// 消息头长度不固定的封包方式（如varint长度）需要实现该接口
+// 直接从数据流中读取一个完整的消息
+type IStreamDataPack interface {
+	IDataPack
+
+	ReadMsg(r io.Reader) (IMessage, error)
+}
@@ -0,0 +1,39 @@
+package zinterface
+
+// IGroupManager 管理命名的连接分组，如聊天频道、游戏房间、租户等
+type IGroupManager interface {
+	// 将连接加入分组，分组不存在时自动创建
+	Join(group string, conn IConnection) error
+
+	// 将连接移出分组，分组没有成员时自动销毁
+	Leave(group string, conn IConnection)
+
+	// 将连接移出所有分组
+	LeaveAll(conn IConnection)
+
+	// 获取分组的所有成员
+	Members(group string) []IConnection
+
+	// 获取分组的成员数
+	Len(group string) int
+
+	// 获取所有分组的名称
+	Groups() []string
+
+	// 获取连接所在的所有分组
+	GroupsOf(conn IConnection) []string
+
+	// 向分组的所有成员发送消息，返回成功放入发送队列的连接数
+	Broadcast(group string, msgId uint32, data []byte) int
+
+	// 向分组中除exceptConnIds以外的成员发送消息，返回成功放入发送队列的连接数
+	BroadcastExcept(group string, msgId uint32, data []byte, exceptConnIds ...uint32) int
+
+	// 设置分组创建和销毁时的回调
+	SetOnGroupCreate(f func(group string))
+	SetOnGroupDestroy(f func(group string))
+
+	// 设置连接加入和离开分组时的回调
+	SetOnJoin(f func(group string, conn IConnection))
+	SetOnLeave(f func(group string, conn IConnection))
+}
@@ -0,0 +1,34 @@
+package zinterface
+
+import "time"
+
+// HeartbeatDirection 心跳的发送方向
+type HeartbeatDirection int
+
+const (
+	// HeartbeatFromClient 客户端发送ping，服务端回复pong
+	HeartbeatFromClient HeartbeatDirection = iota
+	// HeartbeatFromServer 服务端发送ping，客户端回复pong
+	HeartbeatFromServer
+	// HeartbeatBoth 双方各自发送ping，不回复
+	HeartbeatBoth
+)
+
+// HeartbeatConfig 心跳协议配置，服务端和客户端需要使用相同的 MsgId 和 Direction
+// 心跳消息在连接的读协程中处理，不经过路由和中间件，其他msgId可以自由使用
+type HeartbeatConfig struct {
+	// 心跳消息的msgId
+	MsgId uint32
+	// 发送ping的间隔
+	Interval time.Duration
+	// 连续多少个间隔没有收到任何数据时认为对端已失效
+	MaxMissed int
+	// 发送方向
+	Direction HeartbeatDirection
+	// 生成ping的消息体，为nil时使用 "ping"
+	MakePing func(conn IConnection) []byte
+	// 生成pong的消息体，为nil时使用 "pong"
+	MakePong func(conn IConnection, ping []byte) []byte
+	// 对端失效时的回调，为nil时关闭连接
+	OnRemoteNotAlive func(conn IConnection)
+}
@@ -0,0 +1,19 @@
+package zinterface
+
+import "crypto/tls"
+
+// ListenerConfig 服务器的一个监听地址，同一服务器的所有监听器共用路由、连接管理和工作池
+type ListenerConfig struct {
+	// 监听器名称，在服务器内唯一，连接通过 IConnection.GetListener 获取，用于区分内网和公网入口
+	Name string
+	// 网络类型："tcp4"、"tcp6"、"tcp"（双栈）或 "unix"
+	Network string
+	// 监听地址，tcp为 "host:port"，unix为socket文件路径
+	Address string
+	// 封包方式，为nil时使用服务器的封包方式
+	DataPack IDataPack
+	// TLS配置，为nil时使用服务器的TLS配置（WithTLS、WithTLSFiles 或配置文件），服务器也没有时不使用TLS
+	TLSConfig *tls.Config
+	// 该监听器的最大连接数，为0时只受服务器的最大连接数限制
+	MaxConn int
+}
@@ -1,5 +1,24 @@
 package zinterface
 
+// 扩展消息头中的标志位
+const (
+	FlagCompressed uint8 = 1 << iota // 消息体已压缩
+	FlagEncrypted                    // 消息体已加密
+	FlagResponse                     // 响应消息
+	FlagError                        // 错误响应，Status 为错误码
+	FlagOneway                       // 单向消息，不需要响应
+)
+
+// 响应的状态码
+const (
+	StatusOK            uint16 = 0
+	StatusBadRequest    uint16 = 400
+	StatusUnauthorized  uint16 = 401
+	StatusNotFound      uint16 = 404
+	StatusInternalError uint16 = 500
+	StatusUnavailable   uint16 = 503
+)
+
 type IMessage interface {
 	GetMsgId() uint32
 	GetDataLen() uint32
@@ -7,4 +26,15 @@ type IMessage interface {
 	SetMsgId(id uint32)
 	SetDataLen(len uint32)
 	SetData(data []byte)
+
+	// 以下字段只在扩展消息头中传输，默认封包方式会忽略
+	// 序列号，用于关联请求和响应
+	GetSeq() uint32
+	SetSeq(seq uint32)
+	// 标志位
+	GetFlags() uint8
+	SetFlags(flags uint8)
+	// 状态码
+	GetStatus() uint16
+	SetStatus(status uint16)
 }
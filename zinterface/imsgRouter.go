@@ -1,7 +1,90 @@
 package zinterface
 
+// HandlerFunc 处理一个请求的函数
+type HandlerFunc func(request IRequest)
+
+// Middleware 中间件，包装下一个处理函数
+// 不调用next即可中断请求
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// UnknownMsgPolicy 收到未注册msgId的消息时的处理策略
+type UnknownMsgPolicy int
+
+const (
+	// UnknownMsgIgnore 记录日志后忽略
+	UnknownMsgIgnore UnknownMsgPolicy = iota
+	// UnknownMsgReplyError 回复 StatusNotFound 的错误响应
+	// 封包方式不传输序列号和标志位时（如默认封包方式）无法回复，按 UnknownMsgIgnore 处理
+	UnknownMsgReplyError
+	// UnknownMsgClose 关闭连接
+	UnknownMsgClose
+)
+
+// Priority 消息的优先级，决定请求在工作池中进入哪个队列
+// 只在工作池为 elastic 模式时生效，ordered 模式的请求不经过优先级队列
+type Priority int
+
+const (
+	// PriorityLow 低优先级，如聊天、广播等可以延后处理的消息
+	PriorityLow Priority = -1
+	// PriorityNormal 默认优先级
+	PriorityNormal Priority = 0
+	// PriorityHigh 高优先级，如登录、心跳、支付等需要优先处理的消息
+	PriorityHigh Priority = 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
 type IMsgRouter interface {
 	DoMsgHandler(req IRequest)
 
 	AddHandler(msgId uint32, handler IHandler)
+
+	// 使用函数注册处理器
+	AddHandlerFunc(msgId uint32, f HandlerFunc)
+
+	// 注册处理器并设置该msgId的优先级
+	AddHandlerWithPriority(msgId uint32, handler IHandler, priority Priority)
+
+	// 设置指定msgId的优先级
+	SetMsgPriority(msgId uint32, priority Priority)
+
+	// 获取msgId的优先级，未设置时为 PriorityNormal
+	GetMsgPriority(msgId uint32) Priority
+
+	// 添加对所有msgId生效的中间件，按添加顺序由外到内执行
+	Use(middlewares ...Middleware)
+
+	// 添加只对指定msgId生效的中间件，在全局中间件之后执行
+	UseFor(msgId uint32, middlewares ...Middleware)
+
+	// 设置未注册msgId的处理器，为nil时按 UnknownMsgPolicy 处理
+	SetNotFoundHandler(handler IHandler)
+
+	// 设置未注册msgId的处理策略
+	SetUnknownMsgPolicy(policy UnknownMsgPolicy)
+
+	// 设置消息体解码失败时的回调
+	SetDecodeErrorHook(hook func(req IRequest, err error))
+
+	// 消息体解码失败时调用
+	OnDecodeError(req IRequest, err error)
+
+	// 设置默认的消息体编解码器
+	SetCodec(codec ICodec)
+
+	// 设置指定msgId的消息体编解码器
+	SetMsgCodec(msgId uint32, codec ICodec)
+
+	// 获取msgId使用的编解码器
+	GetCodec(msgId uint32) ICodec
 }
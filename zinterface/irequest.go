@@ -6,4 +6,26 @@ type IRequest interface {
 	GetMsgData() []byte
 
 	GetMsgID() uint32
+
+	// 扩展消息头中的字段
+	GetSeq() uint32
+
+	GetFlags() uint8
+
+	GetStatus() uint16
+
+	// 使用相同的msgId和序列号回复请求
+	// 封包方式需要传输序列号和标志位（如 utils.NewExtDataPack()），否则不回复并返回错误
+	Reply(data []byte) error
+
+	// 回复一个错误响应，要求同 Reply
+	ReplyError(status uint16, data []byte) error
+
+	// 设置请求范围内的值，用于中间件和处理器之间传递数据
+	Set(key string, value any)
+
+	Get(key string) (any, bool)
+
+	// 使用该msgId的编解码器将消息体解码到v
+	Bind(v any) error
 }
@@ -1,19 +1,79 @@
 package zinterface
 
+import "context"
+
 // 定义一个服务器接口
 type IServer interface {
 	// 启动服务器
 	Start()
 	// 运行服务器
 	Serve()
+	// 运行服务器，直到ctx结束后优雅关闭
+	ServeContext(ctx context.Context) error
 	// 停止服务器
 	Stop()
+	// 优雅关闭服务器，超过ctx的截止时间时返回错误
+	Shutdown(ctx context.Context) error
+	// 设置 Serve 是否处理 SIGINT/SIGTERM
+	SetSignalHandling(enable bool)
 
 	// 路由功能：给当前的服务注册一个路由方法
 	AddHandler(msgId uint32, handler IHandler)
 
+	// 使用函数注册处理器
+	AddHandlerFunc(msgId uint32, f HandlerFunc)
+
+	// 注册处理器并设置该msgId的优先级
+	AddHandlerWithPriority(msgId uint32, handler IHandler, priority Priority)
+
+	// 设置指定msgId的优先级
+	SetMsgPriority(msgId uint32, priority Priority)
+
+	GetMsgRouter() IMsgRouter
+
+	// 设置默认的消息体编解码器
+	SetCodec(codec ICodec)
+
+	// 设置指定msgId的消息体编解码器
+	SetMsgCodec(msgId uint32, codec ICodec)
+
+	// 添加对所有msgId生效的中间件
+	Use(middlewares ...Middleware)
+
+	// 添加只对指定msgId生效的中间件
+	UseFor(msgId uint32, middlewares ...Middleware)
+
+	// 设置未注册msgId的处理器
+	SetNotFoundHandler(handler IHandler)
+
+	// 设置未注册msgId的处理策略
+	SetUnknownMsgPolicy(policy UnknownMsgPolicy)
+
 	GetConnManager() IConnManager
 
+	// 获取连接分组管理器
+	GetGroupManager() IGroupManager
+
+	// 添加监听器，服务器运行中添加时立即开始监听
+	AddListener(cfg ListenerConfig) error
+
+	// 获取所有监听器的配置
+	GetListeners() []ListenerConfig
+
+	// 重新加载TLS证书文件，已建立的连接不受影响
+	ReloadTLS() error
+
+	// 设置心跳协议，为nil时关闭心跳，需要在 Start 之前调用
+	SetHeartbeatConfig(cfg *HeartbeatConfig)
+
+	// 获取心跳协议，未开启时返回nil
+	GetHeartbeatConfig() *HeartbeatConfig
+
+	// 设置封包方式，需要在 Start 之前调用
+	SetDataPack(dp IDataPack)
+
+	GetDataPack() IDataPack
+
 	SetOnConnStart(func(connection IConnection))
 
 	SetOnConnStop(func(connection IConnection))
@@ -21,7 +81,7 @@ type IServer interface {
 	CallOnConnStart(connection IConnection)
 
 	CallOnConnStop(connection IConnection)
-	
+
 	// 获取工作池
 	GetWorkerPool() interface{}
 }
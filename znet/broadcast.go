@@ -0,0 +1,83 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+)
+
+// multicast 向一组连接发送消息，返回成功放入发送队列的连接数
+// 消息对每种封包方式只封包一次，发送队列已满的连接会被跳过
+func multicast(conns []zinterface.IConnection, msgId uint32, data []byte) int {
+	msg := NewMsgPackage(msgId, data)
+	var packer sharedPacker
+	sent := 0
+	for _, conn := range conns {
+		if err := packer.trySend(conn, msg); err != nil {
+			loggerOf(conn).Debug("multicast msgId = %d to connID = %d skipped: %v", msgId, conn.GetConnId(), err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// excludeConns 去掉connIds中的连接，会复用conns的底层数组
+func excludeConns(conns []zinterface.IConnection, connIds []uint32) []zinterface.IConnection {
+	if len(connIds) == 0 {
+		return conns
+	}
+
+	except := make(map[uint32]struct{}, len(connIds))
+	for _, connId := range connIds {
+		except[connId] = struct{}{}
+	}
+
+	targets := conns[:0]
+	for _, conn := range conns {
+		if _, ok := except[conn.GetConnId()]; !ok {
+			targets = append(targets, conn)
+		}
+	}
+	return targets
+}
+
+// packedSender 可以直接发送已封包数据的连接
+type packedSender interface {
+	trySendPacked(data []byte) error
+}
+
+// sharedPacker 向多个连接发送同一个消息时使用，每种封包方式只封包一次
+type sharedPacker struct {
+	dataPacks []zinterface.IDataPack
+	packed    [][]byte
+}
+
+// pack 获取消息按dp封包后的数据
+func (p *sharedPacker) pack(dp zinterface.IDataPack, msg zinterface.IMessage) ([]byte, error) {
+	for i, packedDp := range p.dataPacks {
+		if packedDp == dp {
+			return p.packed[i], nil
+		}
+	}
+
+	data, err := dp.Pack(msg)
+	if err != nil {
+		return nil, err
+	}
+	p.dataPacks = append(p.dataPacks, dp)
+	p.packed = append(p.packed, data)
+	return data, nil
+}
+
+// trySend 向连接发送消息，不会阻塞
+func (p *sharedPacker) trySend(conn zinterface.IConnection, msg zinterface.IMessage) error {
+	sender, ok := conn.(packedSender)
+	if !ok {
+		return conn.TrySendMsg(msg.GetMsgId(), msg.GetData())
+	}
+
+	data, err := p.pack(conn.GetDataPack(), msg)
+	if err != nil {
+		return err
+	}
+	return sender.trySendPacked(data)
+}
@@ -0,0 +1,157 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrConnectionClosed 连接已关闭，等待中的调用全部失败
+var ErrConnectionClosed = errors.New("zinx: connection closed")
+
+// ErrSendQueueFull 连接的发送队列已满
+var ErrSendQueueFull = errors.New("zinx: send queue full")
+
+// ErrSeqUnsupported 封包方式不传输序列号，无法关联请求和响应
+var ErrSeqUnsupported = errors.New("zinx: datapack does not carry sequence numbers, use utils.NewExtDataPack()")
+
+// CallError 对端返回的错误响应
+type CallError struct {
+	MsgId  uint32
+	Status uint16
+	Data   []byte
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("zinx: call msgId = %d failed, status = %d: %s", e.MsgId, e.Status, e.Data)
+}
+
+// PendingCalls 管理一个连接上等待响应的调用
+// 通过序列号关联请求和响应，服务端和客户端的连接共用该实现
+type PendingCalls struct {
+	nextSeq atomic.Uint32
+	calls   map[uint32]chan zinterface.IMessage
+	lock    sync.Mutex
+	closed  bool
+	// 记录等待中调用数的性能指标
+	metrics *utils.Metrics
+}
+
+func NewPendingCalls() *PendingCalls {
+	return &PendingCalls{
+		calls:   make(map[uint32]chan zinterface.IMessage),
+		metrics: utils.GlobalMetrics,
+	}
+}
+
+// Call 分配序列号并通过send发送请求，阻塞等待响应或ctx结束
+func (p *PendingCalls) Call(ctx context.Context, dp zinterface.IDataPack, send func(msg zinterface.IMessage) error, msgId uint32, data []byte) ([]byte, error) {
+	if !utils.CarriesSeq(dp) {
+		return nil, ErrSeqUnsupported
+	}
+
+	seq := p.allocSeq()
+	respChan := make(chan zinterface.IMessage, 1)
+
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil, ErrConnectionClosed
+	}
+	p.calls[seq] = respChan
+	p.lock.Unlock()
+	p.metrics.IncrementPendingCalls()
+
+	msg := NewMsgPackage(msgId, data)
+	msg.Seq = seq
+	if err := send(msg); err != nil {
+		p.remove(seq)
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-respChan:
+		if !ok {
+			return nil, ErrConnectionClosed
+		}
+		if resp.GetFlags()&zinterface.FlagError != 0 {
+			return nil, &CallError{MsgId: resp.GetMsgId(), Status: resp.GetStatus(), Data: resp.GetData()}
+		}
+		return resp.GetData(), nil
+	case <-ctx.Done():
+		p.remove(seq)
+		return nil, ctx.Err()
+	}
+}
+
+// Deliver 处理响应帧，不是响应帧时返回false
+// 响应交给等待中的调用；没有对应的调用时（调用已超时，或回复的是单向消息）丢弃并计数，
+// 响应帧不会交给路由，避免两端的处理器互相回复
+func (p *PendingCalls) Deliver(msg zinterface.IMessage) bool {
+	if msg.GetFlags()&zinterface.FlagResponse == 0 {
+		return false
+	}
+
+	p.lock.Lock()
+	respChan, ok := p.calls[msg.GetSeq()]
+	if ok {
+		delete(p.calls, msg.GetSeq())
+	}
+	p.lock.Unlock()
+
+	if !ok {
+		p.metrics.IncrementDroppedResponses()
+		return true
+	}
+	p.metrics.DecrementPendingCalls()
+	respChan <- msg
+	return true
+}
+
+// Close 连接关闭时调用，所有等待中的调用返回 ErrConnectionClosed
+func (p *PendingCalls) Close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+
+	for seq, respChan := range p.calls {
+		close(respChan)
+		delete(p.calls, seq)
+		p.metrics.DecrementPendingCalls()
+	}
+}
+
+// Len 获取等待响应的调用数
+func (p *PendingCalls) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.calls)
+}
+
+// allocSeq 分配一个非0的序列号，0表示请求不需要关联响应
+func (p *PendingCalls) allocSeq() uint32 {
+	for {
+		if seq := p.nextSeq.Add(1); seq != 0 {
+			return seq
+		}
+	}
+}
+
+func (p *PendingCalls) remove(seq uint32) {
+	p.lock.Lock()
+	_, ok := p.calls[seq]
+	delete(p.calls, seq)
+	p.lock.Unlock()
+
+	if ok {
+		p.metrics.DecrementPendingCalls()
+	}
+}
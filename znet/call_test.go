@@ -0,0 +1,194 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestCalls 创建使用独立性能指标的 PendingCalls
+func newTestCalls() *PendingCalls {
+	p := NewPendingCalls()
+	p.metrics = utils.NewMetrics()
+	return p
+}
+
+// waitCalls 等待 n 个调用进入等待响应的状态
+func waitCalls(t *testing.T, p *PendingCalls, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d pending calls, want %d", p.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCallSeqUnsupported(t *testing.T) {
+	p := newTestCalls()
+	for _, dp := range []zinterface.IDataPack{utils.NewDataPackUtil(), utils.NewVarintDataPack()} {
+		sent := false
+		send := func(msg zinterface.IMessage) error {
+			sent = true
+			return nil
+		}
+		if _, err := p.Call(context.Background(), dp, send, 1, nil); !errors.Is(err, ErrSeqUnsupported) {
+			t.Fatalf("Call with %T = %v, want ErrSeqUnsupported", dp, err)
+		}
+		if sent {
+			t.Fatalf("Call with %T sent the request", dp)
+		}
+	}
+	if p.Len() != 0 {
+		t.Fatalf("%d pending calls after rejected calls", p.Len())
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	p := newTestCalls()
+	var seq uint32
+	send := func(msg zinterface.IMessage) error {
+		seq = msg.GetSeq()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Call(ctx, utils.NewExtDataPack(), send, 1, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call = %v, want context.DeadlineExceeded", err)
+	}
+	if p.Len() != 0 || p.metrics.GetPendingCalls() != 0 {
+		t.Fatalf("after timeout Len = %d, PendingCalls = %d, want 0", p.Len(), p.metrics.GetPendingCalls())
+	}
+
+	// 超时后到达的响应被丢弃
+	resp := NewMsgPackage(1, nil)
+	resp.Seq = seq
+	resp.Flags = zinterface.FlagResponse
+	if !p.Deliver(resp) {
+		t.Fatal("Deliver returned false for a response")
+	}
+	if p.metrics.DroppedResponses != 1 {
+		t.Fatalf("DroppedResponses = %d, want 1", p.metrics.DroppedResponses)
+	}
+}
+
+func TestCallResponse(t *testing.T) {
+	p := newTestCalls()
+	sent := make(chan zinterface.IMessage, 2)
+	send := func(msg zinterface.IMessage) error {
+		sent <- msg
+		return nil
+	}
+	call := func(data string) ([]byte, error) {
+		return p.Call(context.Background(), utils.NewExtDataPack(), send, 1, []byte(data))
+	}
+
+	go func() {
+		req := <-sent
+		resp := NewMsgPackage(req.GetMsgId(), append([]byte("re: "), req.GetData()...))
+		resp.Seq = req.GetSeq()
+		resp.Flags = zinterface.FlagResponse
+		p.Deliver(resp)
+
+		req = <-sent
+		resp = NewMsgPackage(req.GetMsgId(), []byte("bad"))
+		resp.Seq = req.GetSeq()
+		resp.Flags = zinterface.FlagResponse | zinterface.FlagError
+		resp.Status = zinterface.StatusBadRequest
+		p.Deliver(resp)
+	}()
+
+	if got, err := call("hello"); err != nil || string(got) != "re: hello" {
+		t.Fatalf("Call = %q, %v, want %q", got, err, "re: hello")
+	}
+	var callErr *CallError
+	if _, err := call("hello"); !errors.As(err, &callErr) || callErr.Status != zinterface.StatusBadRequest {
+		t.Fatalf("Call = %v, want CallError with StatusBadRequest", err)
+	}
+
+	// 不是响应的消息交给路由
+	if p.Deliver(NewMsgPackage(1, nil)) {
+		t.Fatal("Deliver returned true for a request")
+	}
+}
+
+func TestCallSendError(t *testing.T) {
+	p := newTestCalls()
+	send := func(msg zinterface.IMessage) error { return ErrSendQueueFull }
+	if _, err := p.Call(context.Background(), utils.NewExtDataPack(), send, 1, nil); !errors.Is(err, ErrSendQueueFull) {
+		t.Fatalf("Call = %v, want ErrSendQueueFull", err)
+	}
+	if p.Len() != 0 || p.metrics.GetPendingCalls() != 0 {
+		t.Fatalf("after send error Len = %d, PendingCalls = %d, want 0", p.Len(), p.metrics.GetPendingCalls())
+	}
+}
+
+func TestCallClose(t *testing.T) {
+	p := newTestCalls()
+	send := func(msg zinterface.IMessage) error { return nil }
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := p.Call(context.Background(), utils.NewExtDataPack(), send, 1, nil)
+			errs <- err
+		}()
+	}
+	waitCalls(t, p, 3)
+
+	// 关闭时所有等待中的调用返回 ErrConnectionClosed
+	p.Close()
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrConnectionClosed) {
+				t.Fatalf("Call = %v, want ErrConnectionClosed", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Call not returned after Close")
+		}
+	}
+	if p.Len() != 0 || p.metrics.GetPendingCalls() != 0 {
+		t.Fatalf("after Close Len = %d, PendingCalls = %d, want 0", p.Len(), p.metrics.GetPendingCalls())
+	}
+
+	// 关闭后的调用直接失败，不发送请求
+	sent := false
+	send = func(msg zinterface.IMessage) error {
+		sent = true
+		return nil
+	}
+	if _, err := p.Call(context.Background(), utils.NewExtDataPack(), send, 1, nil); !errors.Is(err, ErrConnectionClosed) || sent {
+		t.Fatalf("Call after Close = %v, sent = %t, want ErrConnectionClosed without sending", err, sent)
+	}
+	p.Close()
+}
+
+func TestConnectionCallPeerClosed(t *testing.T) {
+	_, _, connA, connB := newPipeEndpoints(t, WithDataPack(utils.NewExtDataPack()))
+	connA.Start()
+	connB.Start()
+
+	// 对端不回复，连接断开时调用返回 ErrConnectionClosed
+	errs := make(chan error, 1)
+	go func() {
+		_, err := connA.Call(context.Background(), 1, []byte("hello"))
+		errs <- err
+	}()
+	waitCalls(t, connA.calls, 1)
+	connB.Stop()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrConnectionClosed) {
+			t.Fatalf("Call = %v, want ErrConnectionClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Call not returned after the peer closed")
+	}
+}
@@ -3,34 +3,116 @@ package znet
 import (
 	"Go_Zinx/utils"
 	"Go_Zinx/zinterface"
+	"bufio"
+	"context"
+	"crypto/tls"
 	"errors"
-	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ConnState 连接的状态
+type ConnState int32
+
+const (
+	// StateConnecting 连接已建立，TLS连接正在握手或读写协程还未启动
+	StateConnecting ConnState = iota
+	// StateActive 读写协程已启动，可以收发消息
+	StateActive
+	// StateClosing 正在关闭，不再接受发送
+	StateClosing
+	// StateClosed 已关闭
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateActive:
+		return "active"
+	case StateClosing:
+		return "closing"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
 type Connection struct {
 	// 隶属Server
 	TCPServer zinterface.IServer
 
-	Conn *net.TCPConn
+	// 底层连接，可能是TCP、TLS或unix连接
+	Conn net.Conn
+
+	// 接受该连接的监听器
+	listener *listener
 
 	ConnID uint32
 
-	isClosed bool
+	// 连接状态，见 ConnState
+	state atomic.Int32
+
+	// 去告知链接已退出的channel，连接关闭时被关闭
+	ExitChan chan struct{}
+
+	// 连接的context，连接关闭时被取消
+	ctx    context.Context
+	cancel context.CancelFunc
 
-	// 去告知链接已退出的channel
-	ExitChan chan bool
+	// 保证Start和Stop只执行一次
+	started  atomic.Bool
+	stopOnce sync.Once
 
-	// 无缓冲管道
+	// 发送队列，长度为所属Server的 SendQueueSize
 	MsgChan chan []byte
+	// 写缓冲区大小
+	writeBufferSize int
+	// 读取的单个消息体的最大长度，为0时不限制
+	maxPackageSize uint32
+
+	// 已提交但还未写入Socket的消息数，包括写缓冲区中的消息
+	pendingWrites int64
+
+	// 最后一次收到和发送数据的时间（UnixNano），用于空闲检测
+	lastReadTime  atomic.Int64
+	lastWriteTime atomic.Int64
+
+	// 等待对端响应的调用
+	calls *PendingCalls
 
 	// 该链接处理的方法Router
 	Router zinterface.IMsgRouter
 
+	// 该链接使用的封包方式
+	dataPack zinterface.IDataPack
+
 	properties     map[string]any
 	propertiesLock sync.RWMutex
+
+	// 日志和性能指标，与所属Server相同
+	logger  *utils.Logger
+	metrics *utils.Metrics
+}
+
+// loggerOf 获取连接所属Server的日志，客户端等其他连接使用全局日志
+func loggerOf(conn zinterface.IConnection) *utils.Logger {
+	if c, ok := conn.(*Connection); ok {
+		return c.logger
+	}
+	return utils.GlobalLogger
+}
+
+// metricsOf 获取连接所属Server的性能指标，客户端等其他连接使用全局性能指标
+func metricsOf(conn zinterface.IConnection) *utils.Metrics {
+	if c, ok := conn.(*Connection); ok {
+		return c.metrics
+	}
+	return utils.GlobalMetrics
 }
 
 func (c *Connection) SetProperty(key string, value any) {
@@ -55,38 +137,90 @@ func (c *Connection) RemoveProperty(key string) {
 	delete(c.properties, key)
 }
 
-func NewConnection(server zinterface.IServer, conn *net.TCPConn, connID uint32, router zinterface.IMsgRouter) *Connection {
+func NewConnection(server zinterface.IServer, conn net.Conn, connID uint32, router zinterface.IMsgRouter) *Connection {
+	return newConnection(server, conn, connID, router, nil)
+}
+
+// newConnection 创建监听器l接受的连接，l为nil时不属于任何监听器
+// 监听器和封包方式在加入 ConnManager 之前设置，遍历连接时不会看到未初始化的字段
+func newConnection(server zinterface.IServer, conn net.Conn, connID uint32, router zinterface.IMsgRouter, l *listener) *Connection {
+	// 使用所属Server的配置，其他 IServer 实现使用全局配置
+	sendQueueSize, writeBufferSize := int(utils.GlobalObject.SendQueueSize), int(utils.GlobalObject.WriteBufferSize)
+	maxPackageSize := utils.GlobalObject.MaxPackageSize
+	logger, metrics := utils.GlobalLogger, utils.GlobalMetrics
+	if s, ok := server.(*Server); ok {
+		sendQueueSize, writeBufferSize = s.sendQueueSize, s.writeBufferSize
+		maxPackageSize = s.maxPackageSize
+		logger, metrics = s.logger, s.metrics
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	c := &Connection{
-		TCPServer:      server,
-		Conn:           conn,
-		ConnID:         connID,
-		isClosed:       false,
-		ExitChan:       make(chan bool, 1),
-		MsgChan:        make(chan []byte),
-		Router:         router,
-		properties:     make(map[string]any),
-		propertiesLock: sync.RWMutex{},
+		TCPServer:       server,
+		Conn:            conn,
+		ConnID:          connID,
+		ExitChan:        make(chan struct{}),
+		ctx:             ctx,
+		cancel:          cancel,
+		MsgChan:         make(chan []byte, sendQueueSize),
+		writeBufferSize: writeBufferSize,
+		maxPackageSize:  maxPackageSize,
+		Router:          router,
+		dataPack:        server.GetDataPack(),
+		calls:           NewPendingCalls(),
+		properties:      make(map[string]any),
+		propertiesLock:  sync.RWMutex{},
+		logger:          logger,
+		metrics:         metrics,
+	}
+	c.calls.metrics = metrics
+	if l != nil {
+		c.listener = l
+		if l.cfg.DataPack != nil {
+			c.dataPack = l.cfg.DataPack
+		}
 	}
 
+	now := time.Now().UnixNano()
+	c.lastReadTime.Store(now)
+	c.lastWriteTime.Store(now)
+
 	// 将conn加入到ConnManager中
 	server.GetConnManager().AddConn(c)
 	return c
 }
 
 func (c *Connection) StartWriter() {
-	defer utils.GlobalLogger.Info("connID = %d Writer stopped", c.ConnID)
-	utils.GlobalLogger.Info("connID = %d Writer Goroutine is running...", c.ConnID)
+	defer c.logger.Info("connID = %d Writer stopped", c.ConnID)
+	c.logger.Info("connID = %d Writer Goroutine is running...", c.ConnID)
+
+	writer := bufio.NewWriterSize(c.Conn, c.writeBufferSize)
+	// 已写入缓冲区但还未刷新到Socket的消息数
+	var buffered int64
 
 	for {
 		select {
 		case data := <-c.MsgChan:
-			if _, err := c.Conn.Write(data); err != nil {
-				utils.GlobalLogger.Errorf("Send data error: %v", err)
-				utils.GlobalMetrics.IncrementErrors()
+			_, err := writer.Write(data)
+			buffered++
+			// 队列中没有更多消息时刷新缓冲区，多个消息合并为一次系统调用
+			if err == nil && len(c.MsgChan) == 0 {
+				err = writer.Flush()
+			}
+			if err != nil {
+				atomic.AddInt64(&c.pendingWrites, -buffered)
+				c.logger.Errorf("Send data error: %v", err)
+				c.metrics.IncrementErrors()
+				c.Stop()
 				return
 			}
 			// 更新性能指标：消息发送
-			utils.GlobalMetrics.IncrementMessagesSent()
+			c.metrics.IncrementMessagesSent()
+			if writer.Buffered() == 0 {
+				atomic.AddInt64(&c.pendingWrites, -buffered)
+				buffered = 0
+				c.lastWriteTime.Store(time.Now().UnixNano())
+			}
 		case <-c.ExitChan:
 			// Reader 退出
 			return
@@ -95,41 +229,38 @@ func (c *Connection) StartWriter() {
 }
 
 func (c *Connection) StartReader() {
-	defer utils.GlobalLogger.Info("connID = %d Reader stopped", c.ConnID)
+	defer c.logger.Info("connID = %d Reader stopped", c.ConnID)
 	defer c.Stop()
-	utils.GlobalLogger.Info("connID = %d Reader Goroutine is running...", c.ConnID)
+	c.logger.Info("connID = %d Reader Goroutine is running...", c.ConnID)
 
+	heartbeat := c.TCPServer.GetHeartbeatConfig()
 	for {
-		dp := utils.NewDataPackUtil()
-
-		headData := make([]byte, dp.GetHeadLen())
-		if _, err := io.ReadFull(c.GetTCPConnection(), headData); err != nil {
-			utils.GlobalLogger.Errorf("read msg head error: %v", err)
-			utils.GlobalMetrics.IncrementErrors()
-			break
-		}
-
-		msg, err := dp.Unpack(headData)
+		msg, err := utils.ReadMessageLimit(c.Conn, c.dataPack, c.maxPackageSize)
 		if err != nil {
-			utils.GlobalLogger.Errorf("unpack error: %v", err)
-			utils.GlobalMetrics.IncrementErrors()
-			break
-		}
-
-		var data []byte
-		if msg.GetDataLen() > 0 {
-			data = make([]byte, msg.GetDataLen())
-			if _, err := io.ReadFull(c.GetTCPConnection(), data); err != nil {
-				utils.GlobalLogger.Errorf("read msg data error: %v", err)
-				utils.GlobalMetrics.IncrementErrors()
-				break
+			// 主动关闭连接导致的读错误不记录
+			if c.IsAlive() {
+				c.logger.Errorf("read msg error: %v", err)
+				c.metrics.IncrementErrors()
 			}
+			break
 		}
 
-		msg.SetData(data)
+		// 任何数据帧都刷新读活动时间
+		c.lastReadTime.Store(time.Now().UnixNano())
 
 		// 更新性能指标：消息接收
-		utils.GlobalMetrics.IncrementMessagesReceived()
+		c.metrics.IncrementMessagesReceived()
+
+		// 心跳消息直接处理，不经过路由
+		if heartbeat != nil && msg.GetMsgId() == heartbeat.MsgId {
+			HandleHeartbeat(heartbeat, c, msg, true)
+			continue
+		}
+
+		// 响应帧交给等待的调用或丢弃，不经过路由
+		if c.calls.Deliver(msg) {
+			continue
+		}
 
 		req := &Request{
 			conn: c,
@@ -143,20 +274,36 @@ func (c *Connection) StartReader() {
 			// 使用工作池处理消息
 			server.WorkerPool.AddRequest(req)
 			// 记录消息处理时间
-			utils.GlobalMetrics.RecordMessageHandlingTime(time.Since(startTime))
+			c.metrics.RecordMessageHandlingTime(time.Since(startTime))
 		} else {
 			// 降级方案：直接使用goroutine处理消息
 			go func() {
 				c.Router.DoMsgHandler(req)
 				// 记录消息处理时间
-				utils.GlobalMetrics.RecordMessageHandlingTime(time.Since(startTime))
+				c.metrics.RecordMessageHandlingTime(time.Since(startTime))
 			}()
 		}
 	}
 }
 
 func (c *Connection) Start() {
-	utils.GlobalLogger.Info("Conn Start... ConnID = %d", c.ConnID)
+	if !c.started.CompareAndSwap(false, true) {
+		return
+	}
+	c.logger.Info("Conn Start... ConnID = %d", c.ConnID)
+
+	// TLS连接先完成握手再进入 StateActive，保证 OnConnStart 中可以获取对端身份
+	// 握手失败的连接没有调用 OnConnStart，关闭时也不调用 OnConnStop
+	if err := c.handshake(); err != nil {
+		c.logger.Warn("connID = %d TLS handshake error: %v", c.ConnID, err)
+		c.metrics.IncrementErrors()
+		c.Stop()
+		return
+	}
+	// 握手期间连接可能已被关闭
+	if !c.state.CompareAndSwap(int32(StateConnecting), int32(StateActive)) {
+		return
+	}
 
 	// 启动当前链接的业务
 	// Read goroutine
@@ -167,34 +314,104 @@ func (c *Connection) Start() {
 	c.TCPServer.CallOnConnStart(c)
 }
 
-func (c *Connection) Stop() {
-	utils.GlobalLogger.Info("Conn Stop..., ConnID = %d", c.ConnID)
-
-	if c.isClosed {
-		return
+// handshake TLS连接完成握手，并将验证通过的对端证书信息放入连接属性
+func (c *Connection) handshake() error {
+	tlsConn, ok := c.Conn.(*tls.Conn)
+	if !ok {
+		return nil
 	}
 
-	// 从心跳检测器中移除
-	if server, ok := c.TCPServer.(*Server); ok && server.HeartbeatChecker != nil {
-		server.HeartbeatChecker.RemoveConnection(c.ConnID)
+	ctx, cancel := context.WithTimeout(c.ctx, TLSHandshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return err
 	}
+	SetPeerIdentity(c, tlsConn.ConnectionState())
+	return nil
+}
 
-	close(c.MsgChan)
-	c.isClosed = true
-	c.TCPServer.CallOnConnStop(c)
-	c.Conn.Close()
-	c.ExitChan <- true
-	close(c.ExitChan)
+// Stop 关闭连接，可以在多个协程中重复调用，只有第一次调用生效
+func (c *Connection) Stop() {
+	c.stopOnce.Do(func() {
+		c.logger.Info("Conn Stop..., ConnID = %d", c.ConnID)
+		prev := ConnState(c.state.Swap(int32(StateClosing)))
+
+		// 从心跳检测器中移除
+		if server, ok := c.TCPServer.(*Server); ok && server.HeartbeatChecker != nil {
+			server.HeartbeatChecker.RemoveConnection(c.ConnID)
+		}
 
-	c.TCPServer.GetConnManager().RemoteConn(c.ConnID)
+		c.calls.Close()
+		// 只有进入过 StateActive（调用过 OnConnStart）的连接才调用 OnConnStop
+		if prev == StateActive {
+			c.TCPServer.CallOnConnStop(c)
+		}
+		// 从所有分组中移除
+		if gm := c.TCPServer.GetGroupManager(); gm != nil {
+			gm.LeaveAll(c)
+		}
+		c.Conn.Close()
+		// 不关闭MsgChan，发送方和写协程通过ExitChan得知连接已关闭
+		close(c.ExitChan)
+		c.cancel()
+
+		c.TCPServer.GetConnManager().RemoteConn(c.ConnID)
+		if c.listener != nil {
+			c.listener.conns.Add(-1)
+		}
+		c.state.Store(int32(StateClosed))
+	})
+}
 
-	return
+// LastReadTime 获取最后一次收到数据的时间
+func (c *Connection) LastReadTime() time.Time {
+	return time.Unix(0, c.lastReadTime.Load())
 }
 
+// LastWriteTime 获取最后一次发送数据的时间
+func (c *Connection) LastWriteTime() time.Time {
+	return time.Unix(0, c.lastWriteTime.Load())
+}
+
+// GetState 获取连接的状态
+func (c *Connection) GetState() ConnState {
+	return ConnState(c.state.Load())
+}
+
+// IsAlive 连接是否处于活跃状态
+func (c *Connection) IsAlive() bool {
+	return c.GetState() == StateActive
+}
+
+// Done 返回连接关闭时被关闭的channel
+func (c *Connection) Done() <-chan struct{} {
+	return c.ExitChan
+}
+
+// Context 获取连接的context，连接关闭时被取消
+func (c *Connection) Context() context.Context {
+	return c.ctx
+}
+
+// GetTCPConnection 获取底层的TCP连接，TLS或unix连接时返回nil
 func (c *Connection) GetTCPConnection() *net.TCPConn {
+	tcpConn, _ := c.Conn.(*net.TCPConn)
+	return tcpConn
+}
+
+// GetConn 获取底层连接
+func (c *Connection) GetConn() net.Conn {
 	return c.Conn
 }
 
+// GetListener 获取接受该连接的监听器名称
+func (c *Connection) GetListener() string {
+	if c.listener == nil {
+		return ""
+	}
+	return c.listener.cfg.Name
+}
+
 func (c *Connection) GetConnId() uint32 {
 	return c.ConnID
 }
@@ -203,24 +420,135 @@ func (c *Connection) RemoteAddr() net.Addr {
 	return c.Conn.RemoteAddr()
 }
 
+// SendMsg 发送数据，发送队列已满时阻塞等待，直到有空位或连接关闭
 func (c *Connection) SendMsg(msgId uint32, data []byte) error {
-	if c.isClosed {
-		return errors.New("Connection is closed")
-	}
+	return c.SendMessage(NewMsgPackage(msgId, data))
+}
+
+// TrySendMsg 发送数据，发送队列已满时立即返回 ErrSendQueueFull
+func (c *Connection) TrySendMsg(msgId uint32, data []byte) error {
+	return c.sendMessage(NewMsgPackage(msgId, data), noWait)
+}
+
+// SendMsgTimeout 发送数据，发送队列已满时最多等待timeout，超时返回 ErrSendQueueFull
+func (c *Connection) SendMsgTimeout(msgId uint32, data []byte, timeout time.Duration) error {
+	return c.sendMessage(NewMsgPackage(msgId, data), timeout)
+}
+
+// SendMessage 发送一个完整的消息，扩展消息头中的字段会一并发送
+func (c *Connection) SendMessage(msg zinterface.IMessage) error {
+	return c.sendMessage(msg, waitForever)
+}
+
+// TrySendMessage 发送一个完整的消息，发送队列已满时立即返回 ErrSendQueueFull
+func (c *Connection) TrySendMessage(msg zinterface.IMessage) error {
+	return c.sendMessage(msg, noWait)
+}
 
-	dp := utils.NewDataPackUtil()
+// 发送队列已满时的等待时间
+const (
+	// noWait 不等待
+	noWait time.Duration = -1
+	// waitForever 一直等待
+	waitForever time.Duration = 0
+)
 
-	binaryMsg, err := dp.Pack(NewMsgPackage(msgId, data))
+// sendMessage 封包后放入发送队列，队列已满时按timeout等待
+func (c *Connection) sendMessage(msg zinterface.IMessage, timeout time.Duration) error {
+	if c.GetState() >= StateClosing {
+		return ErrConnectionClosed
+	}
+
+	binaryMsg, err := c.dataPack.Pack(msg)
 	if err != nil {
-		utils.GlobalLogger.Errorf("Pack error msg id = %d", msgId)
+		c.logger.Errorf("Pack error msg id = %d", msg.GetMsgId())
 		return errors.New("pack error msg")
 	}
 
-	c.MsgChan <- binaryMsg
+	return c.enqueue(binaryMsg, timeout)
+}
+
+// trySendPacked 将已封包的数据放入发送队列，队列已满时立即返回 ErrSendQueueFull
+// 多个连接可以共享同一份数据，发送过程中不会修改data
+func (c *Connection) trySendPacked(data []byte) error {
+	if c.GetState() >= StateClosing {
+		return ErrConnectionClosed
+	}
+	return c.enqueue(data, noWait)
+}
+
+// enqueue 将已封包的数据放入发送队列
+func (c *Connection) enqueue(data []byte, timeout time.Duration) error {
+	atomic.AddInt64(&c.pendingWrites, 1)
+
+	select {
+	case c.MsgChan <- data:
+		return nil
+	default:
+	}
+
+	err := ErrSendQueueFull
+	if timeout != noWait {
+		var expired <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		select {
+		case c.MsgChan <- data:
+			return nil
+		case <-expired:
+		case <-c.ExitChan:
+			err = ErrConnectionClosed
+		}
+	}
+
+	atomic.AddInt64(&c.pendingWrites, -1)
+	return err
+}
 
+// SendObject 使用路由中该msgId的编解码器编码v后发送
+func (c *Connection) SendObject(msgId uint32, v any) error {
+	data, err := c.Router.GetCodec(msgId).Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.SendMsg(msgId, data)
+}
+
+// Call 向对端发送请求并等待响应，对端通过 IRequest.Reply 回复
+// 需要使用传输序列号的封包方式，如 utils.NewExtDataPack()
+func (c *Connection) Call(ctx context.Context, msgId uint32, data []byte) ([]byte, error) {
+	return c.calls.Call(ctx, c.dataPack, c.SendMessage, msgId, data)
+}
+
+// GetPendingCalls 获取等待响应的调用数
+func (c *Connection) GetPendingCalls() int {
+	return c.calls.Len()
+}
+
+// Flush 等待已提交的消息全部写入Socket，超过ctx的截止时间时返回错误
+func (c *Connection) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&c.pendingWrites) > 0 && c.IsAlive() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 	return nil
 }
 
+// GetDataPack 获取连接使用的封包方式
+func (c *Connection) GetDataPack() zinterface.IDataPack {
+	return c.dataPack
+}
+
 // GetRouter 获取连接的路由
 func (c *Connection) GetRouter() zinterface.IMsgRouter {
 	return c.Router
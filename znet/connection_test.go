@@ -0,0 +1,203 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestConn 创建一个使用 net.Pipe 的连接，对端持续读取并丢弃数据
+func newTestConn(t *testing.T) (*Server, *Connection) {
+	t.Helper()
+	s := NewServer(WithHeartbeat(nil)).(*Server)
+	local, remote := net.Pipe()
+	go io.Copy(io.Discard, remote)
+
+	c := NewConnection(s, local, 1, s.msgRouter)
+	c.Start()
+	t.Cleanup(func() {
+		remote.Close()
+		s.Stop()
+	})
+	return s, c
+}
+
+func TestConnectionConcurrentStop(t *testing.T) {
+	s, c := newTestConn(t)
+	var stops atomic.Int32
+	s.SetOnConnStop(func(conn zinterface.IConnection) { stops.Add(1) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Stop()
+		}()
+	}
+	wg.Wait()
+
+	if got := stops.Load(); got != 1 {
+		t.Fatalf("OnConnStop called %d times, want 1", got)
+	}
+	if c.GetState() != StateClosed {
+		t.Fatalf("state = %v, want closed", c.GetState())
+	}
+	if c.IsAlive() {
+		t.Fatal("IsAlive() = true after Stop")
+	}
+	if _, err := s.GetConnManager().GetConn(c.GetConnId()); err == nil {
+		t.Fatal("connection still in ConnManager after Stop")
+	}
+}
+
+func TestConnectionSendDuringStop(t *testing.T) {
+	_, c := newTestConn(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := c.SendMsg(1, []byte("data")); err != nil && !errors.Is(err, ErrConnectionClosed) {
+					t.Errorf("SendMsg error: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				err := c.TrySendMsg(1, []byte("data"))
+				if err != nil && !errors.Is(err, ErrConnectionClosed) && !errors.Is(err, ErrSendQueueFull) {
+					t.Errorf("TrySendMsg error: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				err := c.SendMsgTimeout(1, []byte("data"), time.Millisecond)
+				if err != nil && !errors.Is(err, ErrConnectionClosed) && !errors.Is(err, ErrSendQueueFull) {
+					t.Errorf("SendMsgTimeout error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	c.Stop()
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("senders blocked after Stop")
+	}
+
+	if err := c.SendMsg(1, nil); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("SendMsg after Stop = %v, want ErrConnectionClosed", err)
+	}
+	if err := c.TrySendMsg(1, nil); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("TrySendMsg after Stop = %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestConnectionDone(t *testing.T) {
+	_, c := newTestConn(t)
+
+	select {
+	case <-c.Done():
+		t.Fatal("Done closed before Stop")
+	default:
+	}
+
+	waiters := 8
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-c.Done()
+		}()
+		go func() {
+			defer wg.Done()
+			<-c.Context().Done()
+		}()
+	}
+
+	go c.Stop()
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done/Context not closed after Stop")
+	}
+	if c.Context().Err() == nil {
+		t.Fatal("Context not canceled after Stop")
+	}
+}
+
+func TestConnectionMaxPackageSize(t *testing.T) {
+	s := NewServer(WithHeartbeat(nil), WithMaxPackageSize(8)).(*Server)
+	defer s.Stop()
+	handled := make(chan []byte, 1)
+	s.AddHandlerFunc(1, func(request zinterface.IRequest) {
+		handled <- request.GetMsgData()
+	})
+
+	local, remote := net.Pipe()
+	defer remote.Close()
+	c := NewConnection(s, local, 1, s.msgRouter)
+	c.Start()
+
+	dp := utils.NewDataPackUtil()
+	send := func(data []byte) {
+		packed, err := dp.Pack(NewMsgPackage(1, data))
+		if err != nil {
+			t.Fatalf("Pack error: %v", err)
+		}
+		// net.Pipe 没有缓冲，超过限制时连接不会读取消息体
+		go remote.Write(packed)
+	}
+
+	send([]byte("12345678"))
+	select {
+	case data := <-handled:
+		if string(data) != "12345678" {
+			t.Fatalf("handled %q, want %q", data, "12345678")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message within MaxPackageSize not handled")
+	}
+
+	send([]byte("123456789"))
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after message larger than MaxPackageSize")
+	}
+	select {
+	case data := <-handled:
+		t.Fatalf("handled oversized message %q", data)
+	default:
+	}
+}
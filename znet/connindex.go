@@ -0,0 +1,191 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"errors"
+)
+
+// ErrKeyConflict 索引值已绑定到其他连接，且索引的策略为 DuplicateKeyReject
+var ErrKeyConflict = errors.New("zinx: key already bound to another connection")
+
+// connIndex 一个二级索引，如 "uid" -> 连接
+type connIndex struct {
+	policy zinterface.DuplicateKeyPolicy
+	// 索引值 -> 绑定的连接，按绑定顺序排列
+	values map[string][]zinterface.IConnection
+}
+
+// indexKey 连接绑定的一个索引值
+type indexKey struct {
+	index string
+	value string
+}
+
+// getIndex 获取索引，不存在时创建
+// 注意：调用此方法前必须持有c.indexLock写锁
+func (c *ConnManager) getIndex(index string) *connIndex {
+	idx, ok := c.indexes[index]
+	if !ok {
+		idx = &connIndex{values: make(map[string][]zinterface.IConnection)}
+		c.indexes[index] = idx
+	}
+	return idx
+}
+
+// SetDuplicateKeyPolicy 设置索引的重复绑定策略，默认为 DuplicateKeyKick
+func (c *ConnManager) SetDuplicateKeyPolicy(index string, policy zinterface.DuplicateKeyPolicy) {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+	c.getIndex(index).policy = policy
+}
+
+// BindKey 在名为index的索引中将value绑定到连接，如登录后 BindKey("uid", uid, conn)
+// value已绑定到其他连接时按索引的 DuplicateKeyPolicy 处理：
+// DuplicateKeyKick 解绑并关闭旧连接，DuplicateKeyReject 返回 ErrKeyConflict，DuplicateKeyAllow 同时保留。
+// 连接关闭时自动解绑，连接已关闭时返回 ErrConnectionClosed
+func (c *ConnManager) BindKey(index string, value string, conn zinterface.IConnection) error {
+	var kicked []zinterface.IConnection
+
+	c.indexLock.Lock()
+	// 在锁内检查连接状态，连接关闭时 RemoteConn 中的解绑一定在此之后执行
+	if !conn.IsAlive() || !c.markBound(conn.GetConnId()) {
+		c.indexLock.Unlock()
+		return ErrConnectionClosed
+	}
+
+	idx := c.getIndex(index)
+	bound := idx.values[value]
+	for _, other := range bound {
+		if other.GetConnId() == conn.GetConnId() {
+			c.indexLock.Unlock()
+			return nil
+		}
+	}
+
+	if len(bound) > 0 {
+		switch idx.policy {
+		case zinterface.DuplicateKeyReject:
+			c.indexLock.Unlock()
+			return ErrKeyConflict
+		case zinterface.DuplicateKeyKick:
+			for _, old := range bound {
+				c.removeConnKey(old.GetConnId(), indexKey{index, value})
+			}
+			kicked = bound
+			bound = nil
+		}
+	}
+
+	idx.values[value] = append(bound, conn)
+	c.connKeys[conn.GetConnId()] = append(c.connKeys[conn.GetConnId()], indexKey{index, value})
+	c.indexLock.Unlock()
+
+	// Stop 会回调 RemoteConn，不能在持有锁的情况下调用
+	for _, old := range kicked {
+		c.logger.Info("%s = %s bound to connID = %d, kick connID = %d",
+			index, value, conn.GetConnId(), old.GetConnId())
+		old.Stop()
+	}
+	return nil
+}
+
+// UnbindKey 解除value与连接的绑定
+func (c *ConnManager) UnbindKey(index string, value string, conn zinterface.IConnection) {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+
+	if c.unbind(index, value, conn.GetConnId()) {
+		c.removeConnKey(conn.GetConnId(), indexKey{index, value})
+	}
+}
+
+// GetByKey 获取绑定到value的连接，有多个时返回最后绑定的
+func (c *ConnManager) GetByKey(index string, value string) (zinterface.IConnection, error) {
+	c.indexLock.RLock()
+	defer c.indexLock.RUnlock()
+
+	if idx, ok := c.indexes[index]; ok {
+		if bound := idx.values[value]; len(bound) > 0 {
+			return bound[len(bound)-1], nil
+		}
+	}
+	return nil, errors.New("Connection Not Found")
+}
+
+// GetAllByKey 获取绑定到value的所有连接
+func (c *ConnManager) GetAllByKey(index string, value string) []zinterface.IConnection {
+	c.indexLock.RLock()
+	defer c.indexLock.RUnlock()
+
+	idx, ok := c.indexes[index]
+	if !ok {
+		return nil
+	}
+	return append([]zinterface.IConnection(nil), idx.values[value]...)
+}
+
+// unbindAll 解除连接的所有绑定，连接从 ConnManager 移除时调用
+// 只对 markBound 标记过的连接调用，没有绑定的连接关闭时不获取 indexLock
+func (c *ConnManager) unbindAll(connId uint32) {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+
+	for _, key := range c.connKeys[connId] {
+		c.unbind(key.index, key.value, connId)
+	}
+	delete(c.connKeys, connId)
+}
+
+// markBound 在分片中标记连接有绑定，连接已从 ConnManager 移除时返回false
+// 在绑定之前标记：标记成功时 RemoteConn 一定会看到标记并解绑；
+// 标记失败说明 RemoteConn 已经执行，不能再绑定
+func (c *ConnManager) markBound(connId uint32) bool {
+	shard := c.shard(connId)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	if _, ok := shard.connections[connId]; !ok {
+		return false
+	}
+	shard.bound[connId] = struct{}{}
+	return true
+}
+
+// unbind 从索引中移除连接，返回连接之前是否绑定了value
+// 注意：调用此方法前必须持有c.indexLock写锁
+func (c *ConnManager) unbind(index string, value string, connId uint32) bool {
+	idx, ok := c.indexes[index]
+	if !ok {
+		return false
+	}
+
+	bound := idx.values[value]
+	for i, conn := range bound {
+		if conn.GetConnId() != connId {
+			continue
+		}
+		if len(bound) == 1 {
+			delete(idx.values, value)
+		} else {
+			idx.values[value] = append(bound[:i:i], bound[i+1:]...)
+		}
+		return true
+	}
+	return false
+}
+
+// removeConnKey 从连接的反向索引中移除一个绑定
+// 注意：调用此方法前必须持有c.indexLock写锁
+func (c *ConnManager) removeConnKey(connId uint32, key indexKey) {
+	keys := c.connKeys[connId]
+	for i, k := range keys {
+		if k == key {
+			keys = append(keys[:i:i], keys[i+1:]...)
+			break
+		}
+	}
+	if len(keys) == 0 {
+		delete(c.connKeys, connId)
+	} else {
+		c.connKeys[connId] = keys
+	}
+}
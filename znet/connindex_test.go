@@ -0,0 +1,157 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// newIndexConns 创建 n 个已启动的连接，ConnID 从1开始，对端持续读取并丢弃数据
+func newIndexConns(t *testing.T, n int) (*ConnManager, []*Connection) {
+	t.Helper()
+	s := NewServer(WithHeartbeat(nil)).(*Server)
+	t.Cleanup(s.Stop)
+	conns := make([]*Connection, n)
+	for i := range conns {
+		local, remote := net.Pipe()
+		go io.Copy(io.Discard, remote)
+		conns[i] = NewConnection(s, local, uint32(i+1), s.msgRouter)
+		conns[i].Start()
+		t.Cleanup(func() { remote.Close() })
+	}
+	return s.connManager.(*ConnManager), conns
+}
+
+// waitClosed 等待连接关闭
+func waitClosed(t *testing.T, conn *Connection) {
+	t.Helper()
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("connID = %d not closed", conn.GetConnId())
+	}
+}
+
+func TestBindKeyKick(t *testing.T) {
+	cm, conns := newIndexConns(t, 2)
+	old, conn := conns[0], conns[1]
+
+	if err := cm.BindKey("uid", "1001", old); err != nil {
+		t.Fatalf("BindKey old: %v", err)
+	}
+	// 默认策略关闭之前绑定的连接
+	if err := cm.BindKey("uid", "1001", conn); err != nil {
+		t.Fatalf("BindKey new: %v", err)
+	}
+	waitClosed(t, old)
+
+	got, err := cm.GetByKey("uid", "1001")
+	if err != nil || got != conn {
+		t.Fatalf("GetByKey = %v, %v, want connID = %d", got, err, conn.GetConnId())
+	}
+	if all := cm.GetAllByKey("uid", "1001"); len(all) != 1 {
+		t.Fatalf("GetAllByKey returned %d connections, want 1", len(all))
+	}
+	if !conn.IsAlive() {
+		t.Fatal("new connection closed")
+	}
+}
+
+func TestBindKeyReject(t *testing.T) {
+	cm, conns := newIndexConns(t, 2)
+	cm.SetDuplicateKeyPolicy("uid", zinterface.DuplicateKeyReject)
+
+	if err := cm.BindKey("uid", "1001", conns[0]); err != nil {
+		t.Fatalf("BindKey first: %v", err)
+	}
+	if err := cm.BindKey("uid", "1001", conns[1]); !errors.Is(err, ErrKeyConflict) {
+		t.Fatalf("BindKey second = %v, want ErrKeyConflict", err)
+	}
+	// 同一连接重复绑定不算冲突
+	if err := cm.BindKey("uid", "1001", conns[0]); err != nil {
+		t.Fatalf("BindKey same connection again: %v", err)
+	}
+
+	got, err := cm.GetByKey("uid", "1001")
+	if err != nil || got != conns[0] {
+		t.Fatalf("GetByKey = %v, %v, want connID = 1", got, err)
+	}
+	if !conns[0].IsAlive() || !conns[1].IsAlive() {
+		t.Fatal("connection closed by reject policy")
+	}
+	cm.indexLock.RLock()
+	_, ok := cm.connKeys[conns[1].GetConnId()]
+	cm.indexLock.RUnlock()
+	if ok {
+		t.Fatal("rejected binding recorded for connID = 2")
+	}
+}
+
+func TestBindKeyAllow(t *testing.T) {
+	cm, conns := newIndexConns(t, 2)
+	cm.SetDuplicateKeyPolicy("room", zinterface.DuplicateKeyAllow)
+
+	for _, conn := range conns {
+		if err := cm.BindKey("room", "lobby", conn); err != nil {
+			t.Fatalf("BindKey connID = %d: %v", conn.GetConnId(), err)
+		}
+	}
+	if all := cm.GetAllByKey("room", "lobby"); len(all) != 2 {
+		t.Fatalf("GetAllByKey returned %d connections, want 2", len(all))
+	}
+	if got, _ := cm.GetByKey("room", "lobby"); got != conns[1] {
+		t.Fatalf("GetByKey = %v, want the last bound connection", got)
+	}
+
+	cm.UnbindKey("room", "lobby", conns[1])
+	if got, _ := cm.GetByKey("room", "lobby"); got != conns[0] {
+		t.Fatalf("GetByKey after UnbindKey = %v, want connID = 1", got)
+	}
+}
+
+func TestBindKeyRemoveConn(t *testing.T) {
+	cm, conns := newIndexConns(t, 2)
+	cm.SetDuplicateKeyPolicy("room", zinterface.DuplicateKeyAllow)
+	conn := conns[0]
+
+	if err := cm.BindKey("uid", "1001", conn); err != nil {
+		t.Fatalf("BindKey uid: %v", err)
+	}
+	for _, c := range conns {
+		if err := cm.BindKey("room", "lobby", c); err != nil {
+			t.Fatalf("BindKey room: %v", err)
+		}
+	}
+
+	// 连接关闭后解除它的所有绑定，不影响其他连接
+	conn.Stop()
+	if _, err := cm.GetByKey("uid", "1001"); err == nil {
+		t.Fatal("uid still bound after the connection was removed")
+	}
+	if all := cm.GetAllByKey("room", "lobby"); len(all) != 1 || all[0] != conns[1] {
+		t.Fatalf("GetAllByKey after remove = %v, want only connID = 2", all)
+	}
+
+	cm.indexLock.RLock()
+	_, keys := cm.connKeys[conn.GetConnId()]
+	_, values := cm.indexes["uid"].values["1001"]
+	cm.indexLock.RUnlock()
+	if keys || values {
+		t.Fatalf("index not cleaned up, connKeys = %t, values = %t", keys, values)
+	}
+	shard := cm.shard(conn.GetConnId())
+	shard.lock.RLock()
+	_, bound := shard.bound[conn.GetConnId()]
+	shard.lock.RUnlock()
+	if bound {
+		t.Fatal("removed connection still marked as bound")
+	}
+
+	// 已关闭的连接不能再绑定
+	if err := cm.BindKey("uid", "1001", conn); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("BindKey closed connection = %v, want ErrConnectionClosed", err)
+	}
+}
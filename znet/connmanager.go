@@ -1,60 +1,172 @@
 package znet
 
 import (
+	"Go_Zinx/utils"
 	"Go_Zinx/zinterface"
 	"errors"
-	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
-type ConnManager struct {
+// ConnShardCount ConnManager 的分片数，必须是2的幂
+const ConnShardCount = 64
+
+// connShard 一个分片，保存 ConnID 落在该分片的连接
+type connShard struct {
 	connections map[uint32]zinterface.IConnection
-	connLock    sync.RWMutex
+	// 调用过 BindKey 的连接，没有绑定的连接关闭时不需要获取全局的 indexLock
+	bound map[uint32]struct{}
+	lock  sync.RWMutex
+}
+
+// ConnManager 按ConnID分片管理连接，不同分片上的增删互不竞争
+type ConnManager struct {
+	shards [ConnShardCount]connShard
+	// 当前连接数
+	count atomic.Int64
+
+	// 按连接属性建立的二级索引，见 BindKey
+	indexes   map[string]*connIndex
+	connKeys  map[uint32][]indexKey
+	indexLock sync.RWMutex
+
+	logger *utils.Logger
 }
 
 func NewConnManager() *ConnManager {
-	return &ConnManager{
-		connections: make(map[uint32]zinterface.IConnection),
-		connLock:    sync.RWMutex{},
+	c := &ConnManager{
+		indexes:  make(map[string]*connIndex),
+		connKeys: make(map[uint32][]indexKey),
+		logger:   utils.GlobalLogger,
 	}
+	for i := range c.shards {
+		c.shards[i].connections = make(map[uint32]zinterface.IConnection)
+		c.shards[i].bound = make(map[uint32]struct{})
+	}
+	return c
+}
+
+// shard 获取connId所在的分片
+func (c *ConnManager) shard(connId uint32) *connShard {
+	return &c.shards[connId&(ConnShardCount-1)]
 }
 
 func (c *ConnManager) AddConn(conn zinterface.IConnection) {
-	c.connLock.Lock()
-	defer c.connLock.Unlock()
-	c.connections[conn.GetConnId()] = conn
-	fmt.Println("connection add to ConnManager")
+	connId := conn.GetConnId()
+	shard := c.shard(connId)
+
+	shard.lock.Lock()
+	if _, ok := shard.connections[connId]; !ok {
+		c.count.Add(1)
+	}
+	shard.connections[connId] = conn
+	shard.lock.Unlock()
 }
 
 func (c *ConnManager) RemoteConn(connId uint32) {
-	c.connLock.Lock()
-	defer c.connLock.Unlock()
-	delete(c.connections, connId)
-	fmt.Println("connId =", connId, "remove from ConnManager")
+	shard := c.shard(connId)
+
+	shard.lock.Lock()
+	if _, ok := shard.connections[connId]; ok {
+		delete(shard.connections, connId)
+		c.count.Add(-1)
+	}
+	_, bound := shard.bound[connId]
+	delete(shard.bound, connId)
+	shard.lock.Unlock()
+
+	if bound {
+		c.unbindAll(connId)
+	}
 }
 
 func (c *ConnManager) GetConn(connId uint32) (zinterface.IConnection, error) {
-	c.connLock.RLock()
-	defer c.connLock.RUnlock()
-	if conn, ok := c.connections[connId]; !ok {
+	shard := c.shard(connId)
+
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+	if conn, ok := shard.connections[connId]; !ok {
 		return nil, errors.New("Connection Not Found")
 	} else {
 		return conn, nil
 	}
 }
 
+// Len 获取当前连接数，不加锁
 func (c *ConnManager) Len() int {
-	return len(c.connections)
+	return int(c.count.Load())
 }
 
 func (c *ConnManager) ClearConn() {
-	c.connLock.Lock()
-	defer c.connLock.Unlock()
-
-	for connId, conn := range c.connections {
+	// Stop 会回调 RemoteConn，不能在持有锁的情况下调用
+	c.Range(func(conn zinterface.IConnection) bool {
 		conn.Stop()
-		delete(c.connections, connId)
+		return true
+	})
+
+	for i := range c.shards {
+		shard := &c.shards[i]
+		shard.lock.Lock()
+		c.count.Add(-int64(len(shard.connections)))
+		clear(shard.connections)
+		shard.lock.Unlock()
 	}
 
-	fmt.Println("Clear All connections success!")
+	c.logger.Info("Clear All connections success!")
+}
+
+// Range 遍历所有连接，f返回false时停止遍历
+// 逐个分片获取快照后遍历，不持有全局锁，f中可以调用 Stop 或增删连接；
+// 遍历期间增删的连接可能被遍历到，也可能不会
+func (c *ConnManager) Range(f func(conn zinterface.IConnection) bool) {
+	var conns []zinterface.IConnection
+	for i := range c.shards {
+		conns = c.shards[i].appendTo(conns[:0])
+		for _, conn := range conns {
+			if !f(conn) {
+				return
+			}
+		}
+	}
+}
+
+// Broadcast 向所有连接发送消息，返回成功放入发送队列的连接数
+// 发送队列已满的连接会被跳过，不会阻塞调用方
+func (c *ConnManager) Broadcast(msgId uint32, data []byte) int {
+	return multicast(c.snapshot(), msgId, data)
+}
+
+// BroadcastExcept 向除exceptConnIds以外的所有连接发送消息，返回成功放入发送队列的连接数
+func (c *ConnManager) BroadcastExcept(msgId uint32, data []byte, exceptConnIds ...uint32) int {
+	return multicast(excludeConns(c.snapshot(), exceptConnIds), msgId, data)
+}
+
+// Multicast 向指定的连接发送消息，不存在的连接会被忽略，返回成功放入发送队列的连接数
+func (c *ConnManager) Multicast(connIds []uint32, msgId uint32, data []byte) int {
+	targets := make([]zinterface.IConnection, 0, len(connIds))
+	for _, connId := range connIds {
+		if conn, err := c.GetConn(connId); err == nil {
+			targets = append(targets, conn)
+		}
+	}
+	return multicast(targets, msgId, data)
+}
+
+// snapshot 获取当前所有连接的快照
+func (c *ConnManager) snapshot() []zinterface.IConnection {
+	conns := make([]zinterface.IConnection, 0, c.Len())
+	for i := range c.shards {
+		conns = c.shards[i].appendTo(conns)
+	}
+	return conns
+}
+
+// appendTo 将分片中的连接追加到conns中
+func (s *connShard) appendTo(conns []zinterface.IConnection) []zinterface.IConnection {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, conn := range s.connections {
+		conns = append(conns, conn)
+	}
+	return conns
 }
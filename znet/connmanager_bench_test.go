@@ -0,0 +1,168 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 模拟的连接数
+const benchConnCount = 100000
+
+// connStore 基准测试中比较的连接管理器
+type connStore interface {
+	AddConn(conn zinterface.IConnection)
+	RemoteConn(connId uint32)
+	GetConn(connId uint32) (zinterface.IConnection, error)
+	Len() int
+}
+
+// mutexConnManager 分片之前的实现（单个map和一把读写锁），作为对比的基准
+// 去掉了原实现中每次增删时的 fmt.Println，只比较锁的开销
+type mutexConnManager struct {
+	connections map[uint32]zinterface.IConnection
+	connLock    sync.RWMutex
+}
+
+func newMutexConnManager() *mutexConnManager {
+	return &mutexConnManager{connections: make(map[uint32]zinterface.IConnection)}
+}
+
+func (c *mutexConnManager) AddConn(conn zinterface.IConnection) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	c.connections[conn.GetConnId()] = conn
+}
+
+func (c *mutexConnManager) RemoteConn(connId uint32) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	delete(c.connections, connId)
+}
+
+func (c *mutexConnManager) GetConn(connId uint32) (zinterface.IConnection, error) {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.connections[connId], nil
+}
+
+func (c *mutexConnManager) Len() int {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return len(c.connections)
+}
+
+// benchConn 只实现 GetConnId 的模拟连接
+type benchConn struct {
+	zinterface.IConnection
+	id uint32
+}
+
+func (c *benchConn) GetConnId() uint32 {
+	return c.id
+}
+
+// connStores 参与比较的实现，每个都预先放入 benchConnCount 个连接
+func connStores() []struct {
+	name  string
+	store func() connStore
+} {
+	return []struct {
+		name  string
+		store func() connStore
+	}{
+		{"sharded", func() connStore { return NewConnManager() }},
+		{"mutex", func() connStore { return newMutexConnManager() }},
+	}
+}
+
+func prefill(store connStore) {
+	for id := uint32(1); id <= benchConnCount; id++ {
+		store.AddConn(&benchConn{id: id})
+	}
+}
+
+// BenchmarkConnManagerAcceptClose 在已有10万连接的情况下，并发地建立并关闭连接
+// 每次迭代模拟一次accept（AddConn + Len 检查 MaxConn）和一次close（RemoteConn）
+func BenchmarkConnManagerAcceptClose(b *testing.B) {
+	for _, tc := range connStores() {
+		b.Run(tc.name, func(b *testing.B) {
+			store := tc.store()
+			prefill(store)
+			var nextID atomic.Uint32
+			nextID.Store(benchConnCount)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					id := nextID.Add(1)
+					store.Len()
+					store.AddConn(&benchConn{id: id})
+					store.RemoteConn(id)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkConnManagerGetConn 在10万连接中并发地查找连接，同时有少量accept/close
+func BenchmarkConnManagerGetConn(b *testing.B) {
+	for _, tc := range connStores() {
+		b.Run(tc.name, func(b *testing.B) {
+			store := tc.store()
+			prefill(store)
+			var counter atomic.Uint32
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := counter.Add(1)
+					if n%16 == 0 {
+						id := benchConnCount + n
+						store.AddConn(&benchConn{id: id})
+						store.RemoteConn(id)
+						continue
+					}
+					store.GetConn(n%benchConnCount + 1)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkConnManagerLen 10万连接时获取连接数
+func BenchmarkConnManagerLen(b *testing.B) {
+	for _, tc := range connStores() {
+		b.Run(tc.name, func(b *testing.B) {
+			store := tc.store()
+			prefill(store)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					store.Len()
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkConnManagerRange 10万连接时遍历所有连接
+func BenchmarkConnManagerRange(b *testing.B) {
+	cm := NewConnManager()
+	prefill(cm)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		cm.Range(func(conn zinterface.IConnection) bool {
+			n++
+			return true
+		})
+		if n != benchConnCount {
+			b.Fatalf("Range visited %d connections, want %d", n, benchConnCount)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"sync"
+)
+
+// GroupManager IGroupManager的实现
+// 回调在锁外执行，可以在回调中调用 GroupManager 的方法
+type GroupManager struct {
+	// 分组名称 -> 成员
+	groups map[string]map[uint32]zinterface.IConnection
+	// 连接ID -> 所在的分组
+	connGroups map[uint32]map[string]struct{}
+	lock       sync.RWMutex
+
+	// hook
+	onGroupCreate  func(group string)
+	onGroupDestroy func(group string)
+	onJoin         func(group string, conn zinterface.IConnection)
+	onLeave        func(group string, conn zinterface.IConnection)
+}
+
+func NewGroupManager() *GroupManager {
+	return &GroupManager{
+		groups:     make(map[string]map[uint32]zinterface.IConnection),
+		connGroups: make(map[uint32]map[string]struct{}),
+	}
+}
+
+// Join 将连接加入分组，分组不存在时自动创建，连接已关闭时返回 ErrConnectionClosed
+func (g *GroupManager) Join(group string, conn zinterface.IConnection) error {
+	connId := conn.GetConnId()
+
+	g.lock.Lock()
+	// 在锁内检查连接状态，连接关闭时 LeaveAll 一定在此之后执行
+	if !conn.IsAlive() {
+		g.lock.Unlock()
+		return ErrConnectionClosed
+	}
+
+	members, exists := g.groups[group]
+	if !exists {
+		members = make(map[uint32]zinterface.IConnection)
+		g.groups[group] = members
+	}
+	if _, ok := members[connId]; ok {
+		g.lock.Unlock()
+		return nil
+	}
+	members[connId] = conn
+
+	groups, ok := g.connGroups[connId]
+	if !ok {
+		groups = make(map[string]struct{})
+		g.connGroups[connId] = groups
+	}
+	groups[group] = struct{}{}
+	g.lock.Unlock()
+
+	if !exists && g.onGroupCreate != nil {
+		g.onGroupCreate(group)
+	}
+	if g.onJoin != nil {
+		g.onJoin(group, conn)
+	}
+	return nil
+}
+
+// Leave 将连接移出分组，分组没有成员时自动销毁
+func (g *GroupManager) Leave(group string, conn zinterface.IConnection) {
+	g.lock.Lock()
+	left, destroyed := g.leave(group, conn.GetConnId())
+	g.lock.Unlock()
+
+	g.afterLeave(group, conn, left, destroyed)
+}
+
+// LeaveAll 将连接移出所有分组，连接关闭时自动调用
+func (g *GroupManager) LeaveAll(conn zinterface.IConnection) {
+	connId := conn.GetConnId()
+
+	g.lock.Lock()
+	var left, destroyed []string
+	for group := range g.connGroups[connId] {
+		if _, isDestroyed := g.leave(group, connId); isDestroyed {
+			destroyed = append(destroyed, group)
+		}
+		left = append(left, group)
+	}
+	g.lock.Unlock()
+
+	for _, group := range left {
+		g.afterLeave(group, conn, true, false)
+	}
+	for _, group := range destroyed {
+		if g.onGroupDestroy != nil {
+			g.onGroupDestroy(group)
+		}
+	}
+}
+
+// leave 将连接移出分组，返回连接是否在分组中，以及分组是否被销毁
+// 注意：调用此方法前必须持有g.lock锁
+func (g *GroupManager) leave(group string, connId uint32) (left bool, destroyed bool) {
+	members, ok := g.groups[group]
+	if !ok {
+		return false, false
+	}
+	if _, ok := members[connId]; !ok {
+		return false, false
+	}
+
+	delete(members, connId)
+	if groups := g.connGroups[connId]; groups != nil {
+		delete(groups, group)
+		if len(groups) == 0 {
+			delete(g.connGroups, connId)
+		}
+	}
+	if len(members) == 0 {
+		delete(g.groups, group)
+		return true, true
+	}
+	return true, false
+}
+
+// afterLeave 在锁外执行离开分组的回调
+func (g *GroupManager) afterLeave(group string, conn zinterface.IConnection, left, destroyed bool) {
+	if left && g.onLeave != nil {
+		g.onLeave(group, conn)
+	}
+	if destroyed && g.onGroupDestroy != nil {
+		g.onGroupDestroy(group)
+	}
+}
+
+// Members 获取分组的所有成员
+func (g *GroupManager) Members(group string) []zinterface.IConnection {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	members := g.groups[group]
+	conns := make([]zinterface.IConnection, 0, len(members))
+	for _, conn := range members {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Len 获取分组的成员数
+func (g *GroupManager) Len(group string) int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return len(g.groups[group])
+}
+
+// Groups 获取所有分组的名称
+func (g *GroupManager) Groups() []string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	groups := make([]string, 0, len(g.groups))
+	for group := range g.groups {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// GroupsOf 获取连接所在的所有分组
+func (g *GroupManager) GroupsOf(conn zinterface.IConnection) []string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	connGroups := g.connGroups[conn.GetConnId()]
+	groups := make([]string, 0, len(connGroups))
+	for group := range connGroups {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Broadcast 向分组的所有成员发送消息，返回成功放入发送队列的连接数
+// 发送队列已满的连接会被跳过，不会阻塞调用方
+func (g *GroupManager) Broadcast(group string, msgId uint32, data []byte) int {
+	return multicast(g.Members(group), msgId, data)
+}
+
+// BroadcastExcept 向分组中除exceptConnIds以外的成员发送消息，返回成功放入发送队列的连接数
+func (g *GroupManager) BroadcastExcept(group string, msgId uint32, data []byte, exceptConnIds ...uint32) int {
+	return multicast(excludeConns(g.Members(group), exceptConnIds), msgId, data)
+}
+
+// SetOnGroupCreate 设置分组创建时的回调
+func (g *GroupManager) SetOnGroupCreate(f func(group string)) {
+	g.onGroupCreate = f
+}
+
+// SetOnGroupDestroy 设置分组销毁时的回调
+func (g *GroupManager) SetOnGroupDestroy(f func(group string)) {
+	g.onGroupDestroy = f
+}
+
+// SetOnJoin 设置连接加入分组时的回调
+func (g *GroupManager) SetOnJoin(f func(group string, conn zinterface.IConnection)) {
+	g.onJoin = f
+}
+
+// SetOnLeave 设置连接离开分组时的回调
+func (g *GroupManager) SetOnLeave(f func(group string, conn zinterface.IConnection)) {
+	g.onLeave = f
+}
@@ -20,3 +20,13 @@ func (br *BaseHandler) Handle(req zinterface.IRequest) {
 func (br *BaseHandler) PostHandle(req zinterface.IRequest) {
 
 }
+
+// funcHandler 将 HandlerFunc 适配为 IHandler
+type funcHandler struct {
+	BaseHandler
+	f zinterface.HandlerFunc
+}
+
+func (h *funcHandler) Handle(req zinterface.IRequest) {
+	h.f(req)
+}
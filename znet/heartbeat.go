@@ -4,41 +4,150 @@ import (
 	"Go_Zinx/utils"
 	"Go_Zinx/zinterface"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// IdleState 连接空闲的类型
+type IdleState int
+
+const (
+	// ReaderIdle 超过指定时间没有收到数据
+	ReaderIdle IdleState = iota
+	// WriterIdle 超过指定时间没有发送数据
+	WriterIdle
+	// AllIdle 超过指定时间既没有收到也没有发送数据
+	AllIdle
+)
+
+func (s IdleState) String() string {
+	switch s {
+	case ReaderIdle:
+		return "reader idle"
+	case WriterIdle:
+		return "writer idle"
+	case AllIdle:
+		return "all idle"
+	default:
+		return "unknown"
+	}
+}
+
+// IdleHandler 连接空闲时的回调
+type IdleHandler func(conn zinterface.IConnection)
+
+// activityTracker 记录最后收发数据时间的连接，Connection 在读写协程中更新
+type activityTracker interface {
+	LastReadTime() time.Time
+	LastWriteTime() time.Time
+}
+
+// idleEntry 一个连接的空闲检测状态
+type idleEntry struct {
+	conn zinterface.IConnection
+	// 加入检测的时间，以及通过 UpdateActiveTime 手动刷新的读活动时间
+	addTime time.Time
+	touched atomic.Int64
+	timers  [3]*WheelTimer
+	// 心跳定时器
+	beat *WheelTimer
+}
+
 // HeartbeatChecker 心跳检测器
+// 根据连接实际收发数据的时间检测空闲，任何数据帧都会刷新活动时间。
+// 每个连接的每种空闲检测是时间轮上的一个定时器：读写数据时只记录时间，
+// 定时器到期时再检查实际空闲了多久，未超时则按剩余时间重新定时
 type HeartbeatChecker struct {
-	connections    map[uint32]zinterface.IConnection
-	lastActiveTime map[uint32]time.Time
-	checkInterval  time.Duration
-	timeout        time.Duration
-	mutex          sync.RWMutex
-	stopChan       chan bool
-	wg             sync.WaitGroup
+	wheel   *TimingWheel
+	entries map[uint32]*idleEntry
+	mutex   sync.RWMutex
+
+	// 各类空闲的超时时间，为0时不检测
+	timeouts [3]time.Duration
+	// 各类空闲的回调，为nil时 ReaderIdle 和 AllIdle 关闭连接，WriterIdle 忽略
+	handlers [3]IdleHandler
+
+	// 心跳配置，为nil时不发送心跳
+	heartbeat *zinterface.HeartbeatConfig
+
+	logger *utils.Logger
 }
 
 // NewHeartbeatChecker 创建心跳检测器
+// checkInterval 为检测精度，timeout 为读空闲超时时间
 func NewHeartbeatChecker(checkInterval, timeout time.Duration) *HeartbeatChecker {
-	return &HeartbeatChecker{
-		connections:    make(map[uint32]zinterface.IConnection),
-		lastActiveTime: make(map[uint32]time.Time),
-		checkInterval:  checkInterval,
-		timeout:        timeout,
-		stopChan:       make(chan bool),
+	hc := &HeartbeatChecker{
+		wheel:   NewTimingWheel(checkInterval),
+		entries: make(map[uint32]*idleEntry),
+		logger:  utils.GlobalLogger,
 	}
+	hc.timeouts[ReaderIdle] = timeout
+	return hc
+}
+
+// SetHeartbeat 设置心跳配置，为nil时不发送心跳也不检测对端失效
+// 只对之后加入的连接生效，需要在服务器启动前调用
+func (hc *HeartbeatChecker) SetHeartbeat(cfg *zinterface.HeartbeatConfig) {
+	hc.heartbeat = cfg
+}
+
+// SetIdleTimeout 设置空闲超时时间，为0时不检测该类空闲
+// 只对之后加入的连接生效，需要在服务器启动前调用
+func (hc *HeartbeatChecker) SetIdleTimeout(state IdleState, timeout time.Duration) {
+	hc.timeouts[state] = timeout
+}
+
+// GetIdleTimeout 获取空闲超时时间
+func (hc *HeartbeatChecker) GetIdleTimeout(state IdleState) time.Duration {
+	return hc.timeouts[state]
+}
+
+// SetOnReadIdle 设置读空闲的回调，为nil时关闭连接
+func (hc *HeartbeatChecker) SetOnReadIdle(f IdleHandler) {
+	hc.handlers[ReaderIdle] = f
+}
+
+// SetOnWriteIdle 设置写空闲的回调，例如发送心跳包
+func (hc *HeartbeatChecker) SetOnWriteIdle(f IdleHandler) {
+	hc.handlers[WriterIdle] = f
+}
+
+// SetOnAllIdle 设置读写空闲的回调，为nil时关闭连接
+func (hc *HeartbeatChecker) SetOnAllIdle(f IdleHandler) {
+	hc.handlers[AllIdle] = f
 }
 
 // AddConnection 添加连接到心跳检测
 func (hc *HeartbeatChecker) AddConnection(conn zinterface.IConnection) {
+	connID := conn.GetConnId()
+	entry := &idleEntry{conn: conn, addTime: time.Now()}
+	entry.touched.Store(entry.addTime.UnixNano())
+
+	// 在锁内创建定时器，保证与 RemoveConnection 和 check 互斥
 	hc.mutex.Lock()
 	defer hc.mutex.Unlock()
 
-	connID := conn.GetConnId()
-	hc.connections[connID] = conn
-	hc.lastActiveTime[connID] = time.Now()
+	if old, ok := hc.entries[connID]; ok {
+		old.stop()
+	}
+	hc.entries[connID] = entry
 
-	utils.GlobalLogger.Info("Add connection %d to heartbeat checker", connID)
+	for state, timeout := range hc.timeouts {
+		if timeout <= 0 {
+			continue
+		}
+		state := IdleState(state)
+		timer := &WheelTimer{wheel: hc.wheel, f: func() { hc.check(entry, state) }}
+		entry.timers[state] = timer
+		timer.Reset(timeout)
+	}
+
+	if cfg := hc.heartbeat; cfg != nil && cfg.Interval > 0 {
+		entry.beat = &WheelTimer{wheel: hc.wheel, f: func() { hc.beat(entry, cfg) }}
+		entry.beat.Reset(cfg.Interval)
+	}
+
+	hc.logger.Debug("Add connection %d to heartbeat checker", connID)
 }
 
 // RemoveConnection 从心跳检测中移除连接
@@ -46,106 +155,195 @@ func (hc *HeartbeatChecker) RemoveConnection(connID uint32) {
 	hc.mutex.Lock()
 	defer hc.mutex.Unlock()
 
-	delete(hc.connections, connID)
-	delete(hc.lastActiveTime, connID)
-
-	utils.GlobalLogger.Info("Remove connection %d from heartbeat checker", connID)
+	if entry, ok := hc.entries[connID]; ok {
+		delete(hc.entries, connID)
+		entry.stop()
+		hc.logger.Debug("Remove connection %d from heartbeat checker", connID)
+	}
 }
 
-// UpdateActiveTime 更新连接的活动时间
+// UpdateActiveTime 手动刷新连接的读活动时间
+// 连接收到的任何数据都会自动刷新，通常不需要调用
 func (hc *HeartbeatChecker) UpdateActiveTime(connID uint32) {
-	hc.mutex.Lock()
-	defer hc.mutex.Unlock()
+	hc.mutex.RLock()
+	defer hc.mutex.RUnlock()
 
-	if _, exists := hc.connections[connID]; exists {
-		hc.lastActiveTime[connID] = time.Now()
+	if entry, exists := hc.entries[connID]; exists {
+		entry.touched.Store(time.Now().UnixNano())
 	}
 }
 
 // Start 开始心跳检测
 func (hc *HeartbeatChecker) Start() {
-	hc.wg.Add(1)
-	go func() {
-		defer hc.wg.Done()
-		utils.GlobalLogger.Info("Heartbeat checker started, check interval: %v, timeout: %v", hc.checkInterval, hc.timeout)
-
-		ticker := time.NewTicker(hc.checkInterval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				hc.checkHeartbeat()
-			case <-hc.stopChan:
-				utils.GlobalLogger.Info("Heartbeat checker stopped")
-				return
-			}
-		}
-	}()
+	hc.wheel.Start()
+	hc.logger.Info("Heartbeat checker started, tick: %v, read idle: %v, write idle: %v, all idle: %v",
+		hc.wheel.tick, hc.timeouts[ReaderIdle], hc.timeouts[WriterIdle], hc.timeouts[AllIdle])
 }
 
 // Stop 停止心跳检测
 func (hc *HeartbeatChecker) Stop() {
-	close(hc.stopChan)
-	hc.wg.Wait()
+	hc.wheel.Stop()
+	hc.logger.Info("Heartbeat checker stopped")
 }
 
-// checkHeartbeat 检查心跳
-func (hc *HeartbeatChecker) checkHeartbeat() {
+// check 定时器到期时检查连接实际空闲了多久
+func (hc *HeartbeatChecker) check(entry *idleEntry, state IdleState) {
+	// 连接已被移除时不再重新定时
 	hc.mutex.RLock()
-	// 创建需要关闭的连接ID列表
-	var toClose []uint32
-	now := time.Now()
+	defer hc.mutex.RUnlock()
+	if hc.entries[entry.conn.GetConnId()] != entry {
+		return
+	}
+
+	timeout := hc.timeouts[state]
+	idle := time.Since(entry.lastActive(state))
+	if idle < timeout {
+		// 期间有数据收发，按剩余时间重新定时
+		entry.timers[state].Reset(timeout - idle)
+		return
+	}
+
+	// 空闲超时，触发回调后重新开始计时
+	entry.timers[state].Reset(timeout)
+	go hc.fire(entry.conn, state, idle)
+}
 
-	for connID, lastActive := range hc.lastActiveTime {
-		if now.Sub(lastActive) > hc.timeout {
-			toClose = append(toClose, connID)
+// beat 每个心跳间隔执行一次：检查对端是否失效，需要时发送ping
+func (hc *HeartbeatChecker) beat(entry *idleEntry, cfg *zinterface.HeartbeatConfig) {
+	hc.mutex.RLock()
+	defer hc.mutex.RUnlock()
+	if hc.entries[entry.conn.GetConnId()] != entry {
+		return
+	}
+	entry.beat.Reset(cfg.Interval)
+
+	conn := entry.conn
+	if time.Since(entry.lastActive(ReaderIdle)) >= HeartbeatTimeout(cfg) {
+		go RemoteNotAlive(cfg, conn)
+		return
+	}
+	if HeartbeatPings(cfg, true) {
+		// 发送队列已满说明连接仍在发送数据，跳过本次ping
+		conn.TrySendMsg(cfg.MsgId, HeartbeatPing(cfg, conn))
+	}
+}
+
+// fire 执行空闲回调
+func (hc *HeartbeatChecker) fire(conn zinterface.IConnection, state IdleState, idle time.Duration) {
+	if handler := hc.handlers[state]; handler != nil {
+		handler(conn)
+		return
+	}
+
+	if state == WriterIdle {
+		return
+	}
+	hc.logger.Warn("Connection %d %s for %v, closing connection", conn.GetConnId(), state, idle)
+	conn.Stop()
+}
+
+// lastActive 获取连接最后一次活动的时间
+func (e *idleEntry) lastActive(state IdleState) time.Time {
+	lastRead := time.Unix(0, e.touched.Load())
+	lastWrite := e.addTime
+	if tracker, ok := e.conn.(activityTracker); ok {
+		if t := tracker.LastReadTime(); t.After(lastRead) {
+			lastRead = t
 		}
+		lastWrite = tracker.LastWriteTime()
 	}
-	hc.mutex.RUnlock()
 
-	// 关闭超时的连接
-	for _, connID := range toClose {
-		hc.mutex.Lock()
-		conn, exists := hc.connections[connID]
-		hc.mutex.Unlock()
+	switch state {
+	case ReaderIdle:
+		return lastRead
+	case WriterIdle:
+		return lastWrite
+	default:
+		if lastWrite.After(lastRead) {
+			return lastWrite
+		}
+		return lastRead
+	}
+}
 
-		if exists {
-			utils.GlobalLogger.Warn("Connection %d heartbeat timeout, closing connection", connID)
-			conn.Stop()
-			hc.RemoveConnection(connID)
+// stop 取消连接的所有定时器
+func (e *idleEntry) stop() {
+	for _, timer := range e.timers {
+		if timer != nil {
+			timer.Stop()
 		}
 	}
+	if e.beat != nil {
+		e.beat.Stop()
+	}
+}
+
+// 默认心跳参数
+const (
+	DefaultHeartbeatInterval  = 5 * time.Second
+	DefaultHeartbeatMaxMissed = 6
+)
+
+// DefaultHeartbeatConfig 默认的心跳配置
+// msgId 0，客户端每5秒发送一次 "ping"，服务端回复 "pong"，30秒没有收到数据时关闭连接
+func DefaultHeartbeatConfig() *zinterface.HeartbeatConfig {
+	return &zinterface.HeartbeatConfig{
+		MsgId:     0,
+		Interval:  DefaultHeartbeatInterval,
+		MaxMissed: DefaultHeartbeatMaxMissed,
+		Direction: zinterface.HeartbeatFromClient,
+	}
 }
 
-// IsHeartbeatMsg 检查是否是心跳消息
-func IsHeartbeatMsg(msgID uint32) bool {
-	// 假设心跳包的消息ID为0
-	return msgID == 0
+// HeartbeatPings 本端是否发送ping
+func HeartbeatPings(cfg *zinterface.HeartbeatConfig, isServer bool) bool {
+	switch cfg.Direction {
+	case zinterface.HeartbeatBoth:
+		return true
+	case zinterface.HeartbeatFromServer:
+		return isServer
+	default:
+		return !isServer
+	}
 }
 
-// CreateHeartbeatMsg 创建心跳消息
-func CreateHeartbeatMsg() zinterface.IMessage {
-	return NewMsgPackage(0, []byte("ping"))
+// HeartbeatPing 生成发送给conn的ping
+func HeartbeatPing(cfg *zinterface.HeartbeatConfig, conn zinterface.IConnection) []byte {
+	if cfg.MakePing != nil {
+		return cfg.MakePing(conn)
+	}
+	return []byte("ping")
 }
 
-// HeartbeatHandler 心跳消息处理器
-type HeartbeatHandler struct {
-	BaseHandler
+// HeartbeatTimeout 对端失效的超时时间
+func HeartbeatTimeout(cfg *zinterface.HeartbeatConfig) time.Duration {
+	maxMissed := cfg.MaxMissed
+	if maxMissed < 1 {
+		maxMissed = 1
+	}
+	return cfg.Interval * time.Duration(maxMissed)
 }
 
-// Handle 处理心跳消息
-func (h *HeartbeatHandler) Handle(request zinterface.IRequest) {
-	// 简单响应pong
-	heartbeatMsg := CreateHeartbeatMsg()
-	heartbeatMsg.SetData([]byte("pong"))
-	request.GetConnection().SendMsg(heartbeatMsg.GetMsgId(), heartbeatMsg.GetData())
+// HandleHeartbeat 处理收到的心跳消息，不发送ping的一端回复pong
+func HandleHeartbeat(cfg *zinterface.HeartbeatConfig, conn zinterface.IConnection, msg zinterface.IMessage, isServer bool) {
+	if HeartbeatPings(cfg, isServer) {
+		return
+	}
 
-	// 更新连接的活动时间
-	conn := request.GetConnection()
-	if checker, err := conn.GetProperty("HeartbeatChecker"); err == nil {
-		if hc, ok := checker.(*HeartbeatChecker); ok {
-			hc.UpdateActiveTime(conn.GetConnId())
-		}
+	pong := []byte("pong")
+	if cfg.MakePong != nil {
+		pong = cfg.MakePong(conn, msg.GetData())
+	}
+	// 发送队列已满时放弃本次回复，不阻塞读协程
+	conn.TrySendMsg(cfg.MsgId, pong)
+}
+
+// RemoteNotAlive 对端失效时调用 OnRemoteNotAlive，未设置时关闭连接
+func RemoteNotAlive(cfg *zinterface.HeartbeatConfig, conn zinterface.IConnection) {
+	if cfg.OnRemoteNotAlive != nil {
+		cfg.OnRemoteNotAlive(conn)
+		return
 	}
+	loggerOf(conn).Warn("Connection %d missed %d heartbeats, closing connection", conn.GetConnId(), cfg.MaxMissed)
+	conn.Stop()
 }
@@ -0,0 +1,54 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeoutKicksConnection(t *testing.T) {
+	_, addr := startTestServer(t, WithIdleTimeout(ReaderIdle, 500*time.Millisecond, nil))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read from idle connection = %v, want EOF", err)
+	}
+	if idle := time.Since(start); idle < 500*time.Millisecond {
+		t.Fatalf("connection closed after %v, before the idle timeout", idle)
+	}
+}
+
+func TestIdleTimeoutHandler(t *testing.T) {
+	idle := make(chan uint32, 1)
+	s, addr := startTestServer(t, WithIdleTimeout(WriterIdle, 500*time.Millisecond, func(conn zinterface.IConnection) {
+		select {
+		case idle <- conn.GetConnId():
+		default:
+		}
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WriterIdle handler not called")
+	}
+	// 写空闲的回调不关闭连接
+	if s.GetConnManager().Len() != 1 {
+		t.Fatalf("connections = %d after WriterIdle, want 1", s.GetConnManager().Len())
+	}
+}
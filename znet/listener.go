@@ -0,0 +1,176 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+)
+
+// DefaultListenerName 未添加监听器时，使用 IPVersion/IP/Port 创建的监听器名称
+const DefaultListenerName = "default"
+
+// listener 服务器的一个监听器
+type listener struct {
+	cfg zinterface.ListenerConfig
+	// 监听中的 net.Listener，未启动或已关闭时为nil，由 Server.listenerLock 保护
+	ln net.Listener
+	// 该监听器当前的连接数
+	conns atomic.Int64
+}
+
+// AddListener 添加监听器，服务器运行中添加时立即开始监听
+func (s *Server) AddListener(cfg zinterface.ListenerConfig) error {
+	if cfg.Name == "" {
+		return errors.New("listener name must not be empty")
+	}
+	switch cfg.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return fmt.Errorf("listener %q: unsupported network %q", cfg.Name, cfg.Network)
+	}
+	if cfg.Address == "" {
+		return fmt.Errorf("listener %q: address must not be empty", cfg.Name)
+	}
+	if cfg.MaxConn < 0 {
+		return fmt.Errorf("listener %q: MaxConn must not be negative, got %d", cfg.Name, cfg.MaxConn)
+	}
+
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	for _, l := range s.listeners {
+		if l.cfg.Name == cfg.Name {
+			return fmt.Errorf("listener %q already exists", cfg.Name)
+		}
+	}
+
+	l := &listener{cfg: cfg}
+	if s.started {
+		if err := s.listen(l); err != nil {
+			return err
+		}
+	}
+	s.listeners = append(s.listeners, l)
+	return nil
+}
+
+// GetListeners 获取所有监听器的配置
+func (s *Server) GetListeners() []zinterface.ListenerConfig {
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	cfgs := make([]zinterface.ListenerConfig, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		cfgs = append(cfgs, l.cfg)
+	}
+	return cfgs
+}
+
+// startListeners 启动所有监听器，任一监听器失败时关闭已启动的监听器
+// 没有添加监听器时，使用 IPVersion/IP/Port 创建默认监听器
+func (s *Server) startListeners() error {
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+
+	if len(s.listeners) == 0 {
+		s.listeners = append(s.listeners, &listener{cfg: zinterface.ListenerConfig{
+			Name:    DefaultListenerName,
+			Network: s.IPVersion,
+			Address: net.JoinHostPort(s.IP, strconv.Itoa(s.Port)),
+		}})
+	}
+
+	for _, l := range s.listeners {
+		if l.ln != nil {
+			continue
+		}
+		if err := s.listen(l); err != nil {
+			for _, opened := range s.listeners {
+				if opened.ln != nil {
+					opened.ln.Close()
+					opened.ln = nil
+				}
+			}
+			return err
+		}
+	}
+	s.started = true
+	return nil
+}
+
+// listen 开始监听并开启 accept 协程
+// 注意：调用此方法前必须持有s.listenerLock锁
+func (s *Server) listen(l *listener) error {
+	// 没有单独设置TLS的监听器使用服务器的TLS配置，避免添加监听器后以明文监听
+	if l.cfg.TLSConfig == nil {
+		l.cfg.TLSConfig = s.tlsConfig
+	}
+	ln, err := net.Listen(l.cfg.Network, l.cfg.Address)
+	if err != nil {
+		return fmt.Errorf("listener %q: listen %s %s error: %w", l.cfg.Name, l.cfg.Network, l.cfg.Address, err)
+	}
+	l.ln = ln
+
+	s.logger.Info("[Start] Server %s listener %q at %s %s", s.Name, l.cfg.Name, l.cfg.Network, ln.Addr())
+	go s.acceptLoop(l, ln)
+	return nil
+}
+
+// closeListeners 关闭所有监听器，不再接受新连接
+func (s *Server) closeListeners() {
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	for _, l := range s.listeners {
+		if l.ln != nil {
+			l.ln.Close()
+			l.ln = nil
+		}
+	}
+	s.started = false
+}
+
+// acceptLoop 阻塞等待客户端连接，处理业务
+func (s *Server) acceptLoop(l *listener, ln net.Listener) {
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			// 监听器已关闭，退出循环
+			if s.shuttingDown.Load() || errors.Is(err, net.ErrClosed) {
+				s.logger.Info("Server %s listener %q closed", s.Name, l.cfg.Name)
+				return
+			}
+			s.logger.Error("Listener %q accept error: %v", l.cfg.Name, err)
+			continue
+		}
+
+		if s.connManager.Len() >= s.maxConn {
+			//  给客户端响应超出最大连接
+			s.logger.Warn("Too Many Connections MaxConn = %d", s.maxConn)
+			conn.Close()
+			continue
+		}
+		if l.conns.Add(1) > int64(l.cfg.MaxConn) && l.cfg.MaxConn > 0 {
+			l.conns.Add(-1)
+			s.logger.Warn("Listener %q too many connections, MaxConn = %d", l.cfg.Name, l.cfg.MaxConn)
+			conn.Close()
+			continue
+		}
+
+		if l.cfg.TLSConfig != nil {
+			conn = tls.Server(conn, l.cfg.TLSConfig)
+		}
+
+		// 使用原子操作递增ConnID，所有监听器共用
+		dealConn := newConnection(s, conn, s.nextConnID.Add(1), s.msgRouter, l)
+
+		// 将连接添加到心跳检测
+		s.HeartbeatChecker.AddConnection(dealConn)
+
+		// 启动链接业务处理
+		go dealConn.Start()
+	}
+}
@@ -0,0 +1,73 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTestServer 在随机端口启动服务器，返回第一个监听器的地址
+func startTestServer(t *testing.T, opts ...Option) (*Server, string) {
+	t.Helper()
+	opts = append([]Option{WithAddress("127.0.0.1", 0), WithHeartbeat(nil)}, opts...)
+	s := NewServer(opts...).(*Server)
+	if err := s.start(); err != nil {
+		t.Fatalf("start server: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	return s, s.listeners[0].ln.Addr().String()
+}
+
+func TestListenerConnVisibleInitialized(t *testing.T) {
+	dp := utils.NewExtDataPack()
+	s, addr := startTestServer(t, WithListener(zinterface.ListenerConfig{
+		Name:     "inner",
+		Network:  "tcp4",
+		Address:  "127.0.0.1:0",
+		DataPack: dp,
+	}))
+
+	// 连接加入 ConnManager 时监听器和封包方式已经设置
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s.GetConnManager().Range(func(conn zinterface.IConnection) bool {
+				if conn.GetListener() != "inner" {
+					t.Errorf("connID = %d listener = %q, want inner", conn.GetConnId(), conn.GetListener())
+				}
+				if conn.GetDataPack() != dp {
+					t.Errorf("connID = %d uses the server datapack", conn.GetConnId())
+				}
+				return true
+			})
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for s.GetConnManager().Len() < 20 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -4,6 +4,9 @@ type Message struct {
 	Id      uint32
 	DataLen uint32
 	Data    []byte
+	Seq     uint32
+	Flags   uint8
+	Status  uint16
 }
 
 func NewMsgPackage(id uint32, data []byte) *Message {
@@ -37,3 +40,27 @@ func (m *Message) SetDataLen(len uint32) {
 func (m *Message) SetData(data []byte) {
 	m.Data = data
 }
+
+func (m *Message) GetSeq() uint32 {
+	return m.Seq
+}
+
+func (m *Message) SetSeq(seq uint32) {
+	m.Seq = seq
+}
+
+func (m *Message) GetFlags() uint8 {
+	return m.Flags
+}
+
+func (m *Message) SetFlags(flags uint8) {
+	m.Flags = flags
+}
+
+func (m *Message) GetStatus() uint16 {
+	return m.Status
+}
+
+func (m *Message) SetStatus(status uint16) {
+	m.Status = status
+}
@@ -0,0 +1,70 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"time"
+)
+
+// Recovery 捕获内层处理器中的panic，使外层的中间件可以继续执行
+// DoMsgHandler 本身也会捕获panic，该中间件用于需要在panic后继续记录日志、统计指标的场景
+func Recovery() zinterface.Middleware {
+	return func(next zinterface.HandlerFunc) zinterface.HandlerFunc {
+		return func(req zinterface.IRequest) {
+			defer func() {
+				if r := recover(); r != nil {
+					logHandlerPanic(req, r)
+				}
+			}()
+			next(req)
+		}
+	}
+}
+
+// RequestLogger 记录每个请求的connID、msgId和处理耗时
+func RequestLogger() zinterface.Middleware {
+	return func(next zinterface.HandlerFunc) zinterface.HandlerFunc {
+		return func(req zinterface.IRequest) {
+			start := time.Now()
+			next(req)
+			loggerOf(req.GetConnection()).Info("[Request] connID = %d msgId = %d len = %d cost = %v",
+				req.GetConnection().GetConnId(), req.GetMsgID(), len(req.GetMsgData()), time.Since(start))
+		}
+	}
+}
+
+// LatencyMetrics 按msgId统计处理耗时
+func LatencyMetrics() zinterface.Middleware {
+	return func(next zinterface.HandlerFunc) zinterface.HandlerFunc {
+		return func(req zinterface.IRequest) {
+			start := time.Now()
+			next(req)
+			metricsOf(req.GetConnection()).RecordMsgHandlingTime(req.GetMsgID(), time.Since(start))
+		}
+	}
+}
+
+// Auth 检查连接是否设置了propertyKey属性（如登录后设置的用户ID），
+// 未设置时回复 StatusUnauthorized 并中断请求。skipMsgIds 中的消息（如登录、心跳）不做检查
+func Auth(propertyKey string, skipMsgIds ...uint32) zinterface.Middleware {
+	skip := make(map[uint32]struct{}, len(skipMsgIds))
+	for _, id := range skipMsgIds {
+		skip[id] = struct{}{}
+	}
+
+	return func(next zinterface.HandlerFunc) zinterface.HandlerFunc {
+		return func(req zinterface.IRequest) {
+			if _, ok := skip[req.GetMsgID()]; ok {
+				next(req)
+				return
+			}
+
+			if _, err := req.GetConnection().GetProperty(propertyKey); err != nil {
+				loggerOf(req.GetConnection()).Warn("connID = %d msgId = %d unauthorized",
+					req.GetConnection().GetConnId(), req.GetMsgID())
+				req.ReplyError(zinterface.StatusUnauthorized, []byte("unauthorized"))
+				return
+			}
+			next(req)
+		}
+	}
+}
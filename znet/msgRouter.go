@@ -1,32 +1,133 @@
 package znet
 
 import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zcodec"
 	"Go_Zinx/zinterface"
 	"fmt"
+	"runtime/debug"
 	"strconv"
 )
 
 type MsgRouter struct {
 	Apis map[uint32]zinterface.IHandler
+
+	// 全局中间件
+	middlewares []zinterface.Middleware
+	// 指定msgId的中间件
+	msgMiddlewares map[uint32][]zinterface.Middleware
+
+	// 未注册msgId的处理器
+	notFoundHandler zinterface.IHandler
+	// 未注册msgId的处理策略，notFoundHandler 为nil时生效
+	unknownMsgPolicy zinterface.UnknownMsgPolicy
+
+	// 消息体解码失败时的回调
+	decodeErrorHook func(req zinterface.IRequest, err error)
+
+	// 默认的消息体编解码器
+	codec zinterface.ICodec
+	// 指定msgId的消息体编解码器
+	msgCodecs map[uint32]zinterface.ICodec
+
+	// 指定msgId的优先级
+	msgPriorities map[uint32]zinterface.Priority
 }
 
 func NewMsgRouter() *MsgRouter {
-	return &MsgRouter{Apis: make(map[uint32]zinterface.IHandler)}
+	return &MsgRouter{
+		Apis:           make(map[uint32]zinterface.IHandler),
+		msgMiddlewares: make(map[uint32][]zinterface.Middleware),
+		codec:          zcodec.NewJSONCodec(),
+		msgCodecs:      make(map[uint32]zinterface.ICodec),
+		msgPriorities:  make(map[uint32]zinterface.Priority),
+	}
 }
 
 // 调度执行对应的消息处理方法
+// 处理器中的panic会被捕获，只影响当前请求
 func (m *MsgRouter) DoMsgHandler(req zinterface.IRequest) {
+	defer func() {
+		if r := recover(); r != nil {
+			logHandlerPanic(req, r)
+		}
+	}()
+
 	id := req.GetMsgID()
 
 	handler, ok := m.Apis[id]
 	if !ok {
-		fmt.Println("api msgId =", req.GetMsgID(), "is NOT FOUND!")
+		m.handleNotFound(req)
+		return
+	}
+
+	m.buildChain(id, handlerFunc(handler))(req)
+}
+
+// handleNotFound 处理未注册msgId的消息，只经过全局中间件
+func (m *MsgRouter) handleNotFound(req zinterface.IRequest) {
+	loggerOf(req.GetConnection()).Warn("api msgId = %d is NOT FOUND, connID = %d", req.GetMsgID(), req.GetConnection().GetConnId())
+	metricsOf(req.GetConnection()).IncrementUnknownMsgs()
+
+	final := m.unknownMsgPolicyFunc()
+	if m.notFoundHandler != nil {
+		final = handlerFunc(m.notFoundHandler)
+	}
+
+	h := final
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		h = m.middlewares[i](h)
 	}
+	h(req)
+}
+
+// unknownMsgPolicyFunc 按 unknownMsgPolicy 处理未注册msgId的消息
+func (m *MsgRouter) unknownMsgPolicyFunc() zinterface.HandlerFunc {
+	return func(req zinterface.IRequest) {
+		switch m.unknownMsgPolicy {
+		case zinterface.UnknownMsgReplyError:
+			// 封包方式不传输标志位时按 UnknownMsgIgnore 处理，
+			// 否则对端使用相同策略时会互相回复 StatusNotFound
+			if utils.CarriesSeq(req.GetConnection().GetDataPack()) {
+				req.ReplyError(zinterface.StatusNotFound, []byte("msgId not found"))
+			}
+		case zinterface.UnknownMsgClose:
+			loggerOf(req.GetConnection()).Warn("connID = %d sent unknown msgId = %d, closing connection",
+				req.GetConnection().GetConnId(), req.GetMsgID())
+			req.GetConnection().Stop()
+		}
+	}
+}
+
+// logHandlerPanic 记录处理器中的panic和调用栈
+func logHandlerPanic(req zinterface.IRequest, r any) {
+	loggerOf(req.GetConnection()).Error("connID = %d msgId = %d handler panic: %v\n%s",
+		req.GetConnection().GetConnId(), req.GetMsgID(), r, debug.Stack())
+	metricsOf(req.GetConnection()).IncrementPanics()
+}
+
+// handlerFunc 将 IHandler 的三段式处理转换为 HandlerFunc
+func handlerFunc(handler zinterface.IHandler) zinterface.HandlerFunc {
+	return func(req zinterface.IRequest) {
+		handler.PreHandle(req)
+		handler.Handle(req)
+		handler.PostHandle(req)
+	}
+}
 
-	handler.PreHandle(req)
-	handler.Handle(req)
-	handler.PostHandle(req)
+// buildChain 用中间件包装处理函数，全局中间件在最外层
+func (m *MsgRouter) buildChain(msgId uint32, final zinterface.HandlerFunc) zinterface.HandlerFunc {
+	h := final
 
+	msgMiddlewares := m.msgMiddlewares[msgId]
+	for i := len(msgMiddlewares) - 1; i >= 0; i-- {
+		h = msgMiddlewares[i](h)
+	}
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		h = m.middlewares[i](h)
+	}
+
+	return h
 }
 
 // 添加具体逻辑
@@ -39,3 +140,84 @@ func (m *MsgRouter) AddHandler(msgId uint32, handler zinterface.IHandler) {
 	m.Apis[msgId] = handler
 	fmt.Println("Add api MsgId =", msgId, "handler =", handler)
 }
+
+// AddHandlerFunc 使用函数注册处理器，无需定义嵌入 BaseHandler 的结构体
+func (m *MsgRouter) AddHandlerFunc(msgId uint32, f zinterface.HandlerFunc) {
+	m.AddHandler(msgId, &funcHandler{f: f})
+}
+
+// AddHandlerWithPriority 注册处理器并设置该msgId的优先级
+func (m *MsgRouter) AddHandlerWithPriority(msgId uint32, handler zinterface.IHandler, priority zinterface.Priority) {
+	m.AddHandler(msgId, handler)
+	m.SetMsgPriority(msgId, priority)
+}
+
+// SetMsgPriority 设置指定msgId的优先级，需要在服务器启动前调用
+// 只在工作池为 elastic 模式时生效，ordered 模式按连接内的到达顺序处理
+func (m *MsgRouter) SetMsgPriority(msgId uint32, priority zinterface.Priority) {
+	m.msgPriorities[msgId] = priority
+}
+
+// GetMsgPriority 获取msgId的优先级，未设置时为 PriorityNormal
+func (m *MsgRouter) GetMsgPriority(msgId uint32) zinterface.Priority {
+	return m.msgPriorities[msgId]
+}
+
+// Use 添加全局中间件，需要在服务器启动前调用
+func (m *MsgRouter) Use(middlewares ...zinterface.Middleware) {
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
+// UseFor 添加指定msgId的中间件，需要在服务器启动前调用
+func (m *MsgRouter) UseFor(msgId uint32, middlewares ...zinterface.Middleware) {
+	m.msgMiddlewares[msgId] = append(m.msgMiddlewares[msgId], middlewares...)
+}
+
+// SetNotFoundHandler 设置未注册msgId的处理器
+func (m *MsgRouter) SetNotFoundHandler(handler zinterface.IHandler) {
+	m.notFoundHandler = handler
+}
+
+// SetUnknownMsgPolicy 设置未注册msgId的处理策略
+func (m *MsgRouter) SetUnknownMsgPolicy(policy zinterface.UnknownMsgPolicy) {
+	m.unknownMsgPolicy = policy
+}
+
+// SetDecodeErrorHook 设置消息体解码失败时的回调
+func (m *MsgRouter) SetDecodeErrorHook(hook func(req zinterface.IRequest, err error)) {
+	m.decodeErrorHook = hook
+}
+
+// OnDecodeError 消息体解码失败时调用，未设置回调时记录日志，
+// 封包方式传输序列号和标志位时还会回复 StatusBadRequest
+func (m *MsgRouter) OnDecodeError(req zinterface.IRequest, err error) {
+	if m.decodeErrorHook != nil {
+		m.decodeErrorHook(req, err)
+		return
+	}
+
+	loggerOf(req.GetConnection()).Warn("connID = %d msgId = %d decode error: %v",
+		req.GetConnection().GetConnId(), req.GetMsgID(), err)
+	// 对端无法区分错误响应和请求时不回复，避免两端互相回复解码错误
+	if utils.CarriesSeq(req.GetConnection().GetDataPack()) {
+		req.ReplyError(zinterface.StatusBadRequest, []byte(err.Error()))
+	}
+}
+
+// SetCodec 设置默认的消息体编解码器，需要在服务器启动前调用
+func (m *MsgRouter) SetCodec(codec zinterface.ICodec) {
+	m.codec = codec
+}
+
+// SetMsgCodec 设置指定msgId的消息体编解码器，需要在服务器启动前调用
+func (m *MsgRouter) SetMsgCodec(msgId uint32, codec zinterface.ICodec) {
+	m.msgCodecs[msgId] = codec
+}
+
+// GetCodec 获取msgId使用的编解码器，未单独设置时使用默认编解码器
+func (m *MsgRouter) GetCodec(msgId uint32) zinterface.ICodec {
+	if codec, ok := m.msgCodecs[msgId]; ok {
+		return codec
+	}
+	return m.codec
+}
@@ -0,0 +1,42 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUnknownMsgReplyError(t *testing.T) {
+	_, b, connA, connB := newPipeEndpoints(t, WithDataPack(utils.NewExtDataPack()))
+	b.SetUnknownMsgPolicy(zinterface.UnknownMsgReplyError)
+	connA.Start()
+	connB.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := connA.Call(ctx, 2, nil)
+	var callErr *CallError
+	if !errors.As(err, &callErr) || callErr.Status != zinterface.StatusNotFound {
+		t.Fatalf("Call unknown msgId = %v, want StatusNotFound", err)
+	}
+}
+
+func TestUnknownMsgReplyErrorWithoutSeq(t *testing.T) {
+	a, b, connA, connB := newPipeEndpoints(t)
+	a.SetUnknownMsgPolicy(zinterface.UnknownMsgReplyError)
+	b.SetUnknownMsgPolicy(zinterface.UnknownMsgReplyError)
+	gotA, gotB := countRequests(a), countRequests(b)
+	connA.Start()
+	connB.Start()
+
+	if err := connA.SendMsg(2, nil); err != nil {
+		t.Fatalf("SendMsg error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if gotB.Load() != 1 || gotA.Load() != 0 {
+		t.Fatalf("a handled %d, b handled %d, want 0 and 1", gotA.Load(), gotB.Load())
+	}
+}
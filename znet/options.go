@@ -0,0 +1,145 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"crypto/tls"
+	"time"
+)
+
+// Option NewServer 的可选参数，未设置的参数使用 utils.GlobalObject 中的配置
+type Option func(s *Server)
+
+// WithName 设置服务器名称
+func WithName(name string) Option {
+	return func(s *Server) {
+		s.Name = name
+	}
+}
+
+// WithAddress 设置默认监听器的地址和端口
+func WithAddress(ip string, port int) Option {
+	return func(s *Server) {
+		s.IP = ip
+		s.Port = port
+	}
+}
+
+// WithListener 添加监听器，可以多次使用；添加后不再创建 IPVersion/IP/Port 的默认监听器
+// 配置不合法时 Start 失败，需要立即检查时使用 IServer.AddListener
+func WithListener(cfg zinterface.ListenerConfig) Option {
+	return func(s *Server) {
+		s.pendingListeners = append(s.pendingListeners, cfg)
+	}
+}
+
+// WithIPVersion 设置默认监听器的网络类型，如 "tcp4"、"tcp6"、"tcp"
+func WithIPVersion(ipVersion string) Option {
+	return func(s *Server) {
+		s.IPVersion = ipVersion
+	}
+}
+
+// WithTLS 没有单独设置 TLSConfig 的监听器使用TLS，优先于 WithTLSFiles 和配置文件中的证书
+func WithTLS(config *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = config
+	}
+}
+
+// WithTLSFiles 没有单独设置 TLSConfig 的监听器使用从文件加载的证书，可以通过 IServer.ReloadTLS 重新加载
+// 未设置时使用配置文件中的 TLS 配置
+func WithTLSFiles(files utils.TLSConfig) Option {
+	return func(s *Server) {
+		s.tlsFiles = files
+	}
+}
+
+// WithDataPack 设置封包方式
+func WithDataPack(dp zinterface.IDataPack) Option {
+	return func(s *Server) {
+		s.dataPack = dp
+	}
+}
+
+// WithMaxPackageSize 设置连接读取的单个消息体的最大长度，为0时不限制
+// 超过限制时关闭连接
+func WithMaxPackageSize(size uint32) Option {
+	return func(s *Server) {
+		s.maxPackageSize = size
+	}
+}
+
+// WithLogger 设置服务器及其连接、工作池、心跳检测使用的日志
+func WithLogger(logger *utils.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithMetrics 设置服务器及其连接、工作池使用的性能指标收集器
+// 未设置时使用 utils.GlobalMetrics，与其他未设置的服务器共用
+func WithMetrics(metrics *utils.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = metrics
+	}
+}
+
+// WithWorkerPoolConfig 设置工作池配置
+func WithWorkerPoolConfig(config utils.WorkerPoolConfig) Option {
+	return func(s *Server) {
+		s.workerPoolConfig = config
+	}
+}
+
+// WithHeartbeat 设置心跳协议，为nil时关闭心跳
+func WithHeartbeat(cfg *zinterface.HeartbeatConfig) Option {
+	return func(s *Server) {
+		s.heartbeat = cfg
+	}
+}
+
+// WithIdleTimeout 设置空闲检测，连接超过timeout没有收发对应的数据时调用handler，timeout为0时不检测
+// handler为nil时 ReaderIdle 和 AllIdle 关闭连接，WriterIdle 忽略；检测精度为1秒
+func WithIdleTimeout(state IdleState, timeout time.Duration, handler IdleHandler) Option {
+	return func(s *Server) {
+		s.idleTimeouts[state] = timeout
+		s.idleHandlers[state] = handler
+	}
+}
+
+// WithMaxConn 设置最大连接数
+func WithMaxConn(maxConn int) Option {
+	return func(s *Server) {
+		s.maxConn = maxConn
+	}
+}
+
+// WithSendQueue 设置每个连接的发送队列长度和写缓冲区大小
+func WithSendQueue(queueSize, writeBufferSize int) Option {
+	return func(s *Server) {
+		s.sendQueueSize = queueSize
+		s.writeBufferSize = writeBufferSize
+	}
+}
+
+// WithShutdownTimeout 设置 Serve/ServeContext 退出时优雅关闭的超时时间
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.ShutdownTimeout = timeout
+	}
+}
+
+// WithOnConnStart 设置连接建立后的回调
+func WithOnConnStart(f func(conn zinterface.IConnection)) Option {
+	return func(s *Server) {
+		s.OnConnStart = f
+	}
+}
+
+// WithOnConnStop 设置连接关闭前的回调
+func WithOnConnStop(f func(conn zinterface.IConnection)) Option {
+	return func(s *Server) {
+		s.OnConnStop = f
+	}
+}
@@ -1,12 +1,28 @@
 package znet
 
-import "Go_Zinx/zinterface"
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"sync"
+)
 
 type Request struct {
 	// 建立好的链接
 	conn zinterface.IConnection
 	// 数据
 	msg zinterface.IMessage
+
+	// 请求范围内的值
+	values     map[string]any
+	valuesLock sync.RWMutex
+}
+
+// NewRequest 创建一个请求，供自定义的连接实现（如客户端）复用路由
+func NewRequest(conn zinterface.IConnection, msg zinterface.IMessage) *Request {
+	return &Request{
+		conn: conn,
+		msg:  msg,
+	}
 }
 
 func (r *Request) GetMsgData() []byte {
@@ -20,3 +36,88 @@ func (r *Request) GetMsgID() uint32 {
 func (r *Request) GetConnection() zinterface.IConnection {
 	return r.conn
 }
+
+// GetSeq 获取请求的序列号
+func (r *Request) GetSeq() uint32 {
+	return r.msg.GetSeq()
+}
+
+// GetFlags 获取请求的标志位
+func (r *Request) GetFlags() uint8 {
+	return r.msg.GetFlags()
+}
+
+// GetStatus 获取请求的状态码
+func (r *Request) GetStatus() uint16 {
+	return r.msg.GetStatus()
+}
+
+// Reply 使用相同的msgId和序列号回复请求，单向消息和响应不回复
+// 封包方式不传输序列号和标志位时，对端无法区分响应和请求，不回复并返回 ErrSeqUnsupported
+func (r *Request) Reply(data []byte) error {
+	return r.reply(zinterface.StatusOK, zinterface.FlagResponse, data)
+}
+
+// ReplyError 回复一个错误响应，status 为错误码
+func (r *Request) ReplyError(status uint16, data []byte) error {
+	return r.reply(status, zinterface.FlagResponse|zinterface.FlagError, data)
+}
+
+func (r *Request) reply(status uint16, flags uint8, data []byte) error {
+	msg, err := newResponse(r, status, flags, data)
+	if msg == nil {
+		return err
+	}
+	return r.conn.SendMessage(msg)
+}
+
+// tryReplyError 不阻塞地回复错误响应，发送队列已满时返回 ErrSendQueueFull
+// 用于在其他连接的协程中回复（如工作池拒绝请求），避免慢连接阻塞调用方
+func tryReplyError(request zinterface.IRequest, status uint16, data []byte) error {
+	msg, err := newResponse(request, status, zinterface.FlagResponse|zinterface.FlagError, data)
+	if msg == nil {
+		return err
+	}
+	return request.GetConnection().TrySendMessage(msg)
+}
+
+// newResponse 创建请求的响应，单向消息和响应不需要回复，返回nil
+// 连接的封包方式不传输序列号和标志位时返回 ErrSeqUnsupported：
+// 响应在对端看来是一个新的请求，双方自动回复错误时会无限循环
+func newResponse(request zinterface.IRequest, status uint16, flags uint8, data []byte) (*Message, error) {
+	if request.GetFlags()&(zinterface.FlagOneway|zinterface.FlagResponse) != 0 {
+		return nil, nil
+	}
+	if !utils.CarriesSeq(request.GetConnection().GetDataPack()) {
+		return nil, ErrSeqUnsupported
+	}
+
+	msg := NewMsgPackage(request.GetMsgID(), data)
+	msg.Seq = request.GetSeq()
+	msg.Flags = flags
+	msg.Status = status
+	return msg, nil
+}
+
+// Set 设置请求范围内的值
+func (r *Request) Set(key string, value any) {
+	r.valuesLock.Lock()
+	defer r.valuesLock.Unlock()
+	if r.values == nil {
+		r.values = make(map[string]any)
+	}
+	r.values[key] = value
+}
+
+// Get 获取请求范围内的值
+func (r *Request) Get(key string) (any, bool) {
+	r.valuesLock.RLock()
+	defer r.valuesLock.RUnlock()
+	value, ok := r.values[key]
+	return value, ok
+}
+
+// Bind 使用路由中该msgId的编解码器将消息体解码到v
+func (r *Request) Bind(v any) error {
+	return r.conn.GetRouter().GetCodec(r.GetMsgID()).Unmarshal(r.GetMsgData(), v)
+}
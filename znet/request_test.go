@@ -0,0 +1,102 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type echoReq struct {
+	Name string
+}
+
+// newPipeEndpoints 创建通过 net.Pipe 相连的两个端点，各自属于一个服务器，默认使用默认封包方式
+func newPipeEndpoints(t *testing.T, opts ...Option) (a, b *Server, connA, connB *Connection) {
+	t.Helper()
+	opts = append([]Option{WithHeartbeat(nil)}, opts...)
+	a = NewServer(opts...).(*Server)
+	b = NewServer(opts...).(*Server)
+	local, remote := net.Pipe()
+	connA = NewConnection(a, local, 1, a.msgRouter)
+	connB = NewConnection(b, remote, 1, b.msgRouter)
+	t.Cleanup(func() {
+		connA.Stop()
+		connB.Stop()
+		a.Stop()
+		b.Stop()
+	})
+	return a, b, connA, connB
+}
+
+// countRequests 统计路由处理的请求数
+func countRequests(s *Server) *atomic.Int32 {
+	var n atomic.Int32
+	s.Use(func(next zinterface.HandlerFunc) zinterface.HandlerFunc {
+		return func(req zinterface.IRequest) {
+			n.Add(1)
+			next(req)
+		}
+	})
+	return &n
+}
+
+func TestReplyWithoutSeqDoesNotLoop(t *testing.T) {
+	a, b, connA, connB := newPipeEndpoints(t)
+	handle := func(ctx context.Context, conn zinterface.IConnection, req *echoReq) (*echoReq, error) {
+		return req, nil
+	}
+	Handle(a.msgRouter, 1, nil, handle)
+	Handle(b.msgRouter, 1, nil, handle)
+	a.SetUnknownMsgPolicy(zinterface.UnknownMsgReplyError)
+	b.SetUnknownMsgPolicy(zinterface.UnknownMsgReplyError)
+	gotA, gotB := countRequests(a), countRequests(b)
+	connA.Start()
+	connB.Start()
+
+	// 解码失败、未注册的msgId和正常的请求都不会回复
+	for _, msg := range []struct {
+		id   uint32
+		data string
+	}{{1, "not json"}, {2, "unknown"}, {1, `{"Name":"zinx"}`}} {
+		if err := connA.SendMsg(msg.id, []byte(msg.data)); err != nil {
+			t.Fatalf("SendMsg error: %v", err)
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if got := gotB.Load(); got != 3 {
+		t.Fatalf("b handled %d requests, want 3", got)
+	}
+	if got := gotA.Load(); got != 0 {
+		t.Fatalf("a handled %d replies, want 0", got)
+	}
+}
+
+func TestReplyWithoutSeq(t *testing.T) {
+	_, b, connA, connB := newPipeEndpoints(t)
+	errs := make(chan error, 2)
+	b.AddHandlerFunc(1, func(request zinterface.IRequest) {
+		errs <- request.Reply([]byte("reply"))
+		errs <- request.ReplyError(zinterface.StatusBadRequest, nil)
+	})
+	connA.Start()
+	connB.Start()
+
+	if err := connA.SendMsg(1, nil); err != nil {
+		t.Fatalf("SendMsg error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrSeqUnsupported) {
+				t.Fatalf("reply error = %v, want ErrSeqUnsupported", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("handler not called")
+		}
+	}
+}
@@ -3,12 +3,24 @@ package znet
 import (
 	"Go_Zinx/utils"
 	"Go_Zinx/zinterface"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"net"
+	"os"
+	"os/signal"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
+// ErrServerClosed 服务器已经关闭或正在关闭
+var ErrServerClosed = errors.New("zinx: server closed")
+
+// DefaultShutdownTimeout Serve/ServeContext 退出时优雅关闭的默认超时时间
+const DefaultShutdownTimeout = 10 * time.Second
+
 // IServer的接口实现，定义一个Server的服务器模块
 type Server struct {
 	Name      string
@@ -22,18 +34,73 @@ type Server struct {
 	// connManager
 	connManager zinterface.IConnManager
 
+	// 连接分组管理器
+	groupManager zinterface.IGroupManager
+
 	// hook
 	OnConnStart func(conn zinterface.IConnection)
 	OnConnStop  func(conn zinterface.IConnection)
 
 	// 心跳检测器
 	HeartbeatChecker *HeartbeatChecker
+	// 心跳协议
+	heartbeat *zinterface.HeartbeatConfig
+	// 各类空闲的超时时间和回调，在 NewServer 中交给 HeartbeatChecker，超时时间为0时不检测
+	idleTimeouts [3]time.Duration
+	idleHandlers [3]IdleHandler
 
 	// 工作池
 	WorkerPool *WorkerPool
+	// 工作池配置
+	workerPoolConfig utils.WorkerPoolConfig
+
+	// 封包方式，默认为 utils.NewDataPackUtil()
+	dataPack zinterface.IDataPack
+
+	// 最大连接数
+	maxConn int
+	// 每个连接的发送队列长度和写缓冲区大小
+	sendQueueSize   int
+	writeBufferSize int
+	// 单个消息体的最大长度，为0时不限制
+	maxPackageSize uint32
+
+	// 日志和性能指标，默认为 utils.GlobalLogger 和 utils.GlobalMetrics
+	logger  *utils.Logger
+	metrics *utils.Metrics
 
 	// 退出通道
 	exitChan chan struct{}
+
+	// 优雅关闭的超时时间，ServeContext 收到退出信号后使用
+	ShutdownTimeout time.Duration
+
+	// 是否在 Serve 中处理 SIGINT/SIGTERM
+	handleSignals bool
+
+	// 监听器，未添加时使用 IPVersion/IP/Port 创建默认监听器
+	listeners    []*listener
+	listenerLock sync.Mutex
+	// 监听器是否已启动
+	started bool
+	// 下一个ConnID，所有监听器共用
+	nextConnID atomic.Uint32
+	// WithListener 添加的监听器，在 NewServer 中逐个添加
+	pendingListeners []zinterface.ListenerConfig
+	// 加载配置文件、添加 pendingListeners 或加载TLS证书时的错误，Start 时返回
+	startErr error
+
+	// 没有单独设置 TLSConfig 的监听器使用的TLS配置，为nil时不使用TLS
+	tlsConfig *tls.Config
+	// 证书文件配置，默认为 utils.GlobalObject.TLS
+	tlsFiles utils.TLSConfig
+	// 从 tlsFiles 加载证书时使用，用于重新加载证书
+	certReloader *CertReloader
+
+	// 是否正在关闭
+	shuttingDown atomic.Bool
+	// 保证资源只释放一次
+	closeOnce sync.Once
 }
 
 func (s *Server) SetOnConnStart(f func(connection zinterface.IConnection)) {
@@ -46,20 +113,20 @@ func (s *Server) SetOnConnStop(f func(connection zinterface.IConnection)) {
 
 func (s *Server) CallOnConnStart(connection zinterface.IConnection) {
 	if s.OnConnStart != nil {
-		utils.GlobalLogger.Info("=========Call OnConnStart()==========")
+		s.logger.Info("=========Call OnConnStart()==========")
 		s.OnConnStart(connection)
 	}
 	// 更新性能指标：连接建立
-	utils.GlobalMetrics.IncrementConnectionsTotal()
+	s.metrics.IncrementConnectionsTotal()
 }
 
 func (s *Server) CallOnConnStop(connection zinterface.IConnection) {
 	if s.OnConnStop != nil {
-		utils.GlobalLogger.Info("=========Call OnConnStop()===========")
+		s.logger.Info("=========Call OnConnStop()===========")
 		s.OnConnStop(connection)
 	}
 	// 更新性能指标：连接关闭
-	utils.GlobalMetrics.DecrementConnectionsCurrent()
+	s.metrics.DecrementConnectionsCurrent()
 }
 
 // Server添加一个Handler
@@ -68,85 +135,204 @@ func (s *Server) AddHandler(msgId uint32, handler zinterface.IHandler) {
 	fmt.Println("Add router Success!")
 }
 
-func (s *Server) Start() {
-	utils.GlobalLogger.Info("[Start] Server Listener at Address: %s:%d", s.IP, s.Port)
+// AddHandlerFunc 使用函数注册处理器
+func (s *Server) AddHandlerFunc(msgId uint32, f zinterface.HandlerFunc) {
+	s.msgRouter.AddHandlerFunc(msgId, f)
+}
 
-	// 开辟一个 go 协程处理服务器启动，防止阻塞
-	go func() {
-		// 1. 获取一个TCP的Addr:Port
-		addr, err := net.ResolveTCPAddr(s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port))
-		if err != nil {
-			utils.GlobalLogger.Error("Resolve TCP Address error: %v", err)
-			return
-		}
+// AddHandlerWithPriority 注册处理器并设置该msgId的优先级
+func (s *Server) AddHandlerWithPriority(msgId uint32, handler zinterface.IHandler, priority zinterface.Priority) {
+	s.msgRouter.AddHandlerWithPriority(msgId, handler, priority)
+}
 
-		// 普通监听
-		listener, err := net.ListenTCP(s.IPVersion, addr)
-		if err != nil {
-			utils.GlobalLogger.Error("Start Server Listener failed: %v", err)
-			return
-		}
+// SetMsgPriority 设置指定msgId的优先级，高优先级的请求在工作池中优先处理
+func (s *Server) SetMsgPriority(msgId uint32, priority zinterface.Priority) {
+	s.msgRouter.SetMsgPriority(msgId, priority)
+}
 
-		defer listener.Close()
+// GetMsgRouter 获取服务器的路由
+func (s *Server) GetMsgRouter() zinterface.IMsgRouter {
+	return s.msgRouter
+}
 
-		utils.GlobalLogger.Info("start Zinx Server success %s Listening", s.Name)
+// SetCodec 设置默认的消息体编解码器
+func (s *Server) SetCodec(codec zinterface.ICodec) {
+	s.msgRouter.SetCodec(codec)
+}
 
-		// 使用原子操作确保ConnID分配的并发安全
-		var cid uint32 = 0
-		// 3. 阻塞等待客户端连接，处理业务
-		for {
-			conn, err := listener.AcceptTCP()
-			if err != nil {
-				utils.GlobalLogger.Error("Accept Error: %v", err)
-				continue
-			}
+// SetMsgCodec 设置指定msgId的消息体编解码器
+func (s *Server) SetMsgCodec(msgId uint32, codec zinterface.ICodec) {
+	s.msgRouter.SetMsgCodec(msgId, codec)
+}
 
-			if s.connManager.Len() >= utils.GlobalObject.MaxConn {
-				//  给客户端响应超出最大连接
-				utils.GlobalLogger.Warn("Too Many Connections MaxConn = %d", utils.GlobalObject.MaxConn)
-				conn.Close()
-				continue
-			}
+// Use 添加对所有msgId生效的中间件
+func (s *Server) Use(middlewares ...zinterface.Middleware) {
+	s.msgRouter.Use(middlewares...)
+}
 
-			// 使用原子操作递增ConnID
-			currentCID := atomic.AddUint32(&cid, 1)
-			dealConn := NewConnection(s, conn, currentCID, s.msgRouter)
+// UseFor 添加只对指定msgId生效的中间件
+func (s *Server) UseFor(msgId uint32, middlewares ...zinterface.Middleware) {
+	s.msgRouter.UseFor(msgId, middlewares...)
+}
 
-			// 将连接添加到心跳检测
-			s.HeartbeatChecker.AddConnection(dealConn)
-			dealConn.SetProperty("HeartbeatChecker", s.HeartbeatChecker)
+// SetNotFoundHandler 设置未注册msgId的处理器
+func (s *Server) SetNotFoundHandler(handler zinterface.IHandler) {
+	s.msgRouter.SetNotFoundHandler(handler)
+}
 
-			// 启动链接业务处理
-			go dealConn.Start()
-		}
-	}()
+// SetUnknownMsgPolicy 设置未注册msgId的处理策略
+func (s *Server) SetUnknownMsgPolicy(policy zinterface.UnknownMsgPolicy) {
+	s.msgRouter.SetUnknownMsgPolicy(policy)
+}
+
+func (s *Server) Start() {
+	if err := s.start(); err != nil {
+		s.logger.Error("Start Server failed: %v", err)
+	}
+}
+
+// start 启动所有监听器
+func (s *Server) start() error {
+	if s.shuttingDown.Load() {
+		return ErrServerClosed
+	}
+	if s.startErr != nil {
+		return s.startErr
+	}
+
+	if err := s.startListeners(); err != nil {
+		return err
+	}
+
+	s.logger.Info("start Zinx Server success %s Listening", s.Name)
+	return nil
+}
+
+// SetSignalHandling 设置 Serve 是否处理 SIGINT/SIGTERM 并优雅关闭
+func (s *Server) SetSignalHandling(enable bool) {
+	s.handleSignals = enable
 }
 
 func (s *Server) Serve() {
-	s.Start()
+	if err := s.ServeContext(context.Background()); err != nil {
+		s.logger.Error("Server %s serve error: %v", s.Name, err)
+	}
+}
+
+// ServeContext 启动服务器并阻塞，直到ctx被取消、收到退出信号或调用了Stop
+// ctx结束后会在 ShutdownTimeout 内优雅关闭服务器
+func (s *Server) ServeContext(ctx context.Context) error {
+	if s.handleSignals {
+		var cancel context.CancelFunc
+		ctx, cancel = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+	}
 
-	// TODO 做一些启动服务器之外的额外业务
+	if err := s.start(); err != nil {
+		return err
+	}
 
-	// 保持阻塞状态
-	select {}
+	select {
+	case <-ctx.Done():
+		s.logger.Info("[STOP] Server %s received exit signal, shutting down", s.Name)
+	case <-s.exitChan:
+		// 已经通过 Stop/Shutdown 关闭
+		return nil
+	}
+
+	timeout := s.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return s.Shutdown(shutdownCtx)
 }
 
+// Stop 立即停止服务器，不等待正在处理的请求
 func (s *Server) Stop() {
-	utils.GlobalLogger.Info("[STOP] Server's ConnManager is closing")
+	s.shuttingDown.Store(true)
+	s.closeListeners()
+
+	s.logger.Info("[STOP] Server's ConnManager is closing")
 	s.connManager.ClearConn()
 
-	// 停止心跳检测器
+	s.release()
+}
+
+// Shutdown 优雅关闭服务器：
+// 停止接收新连接，等待工作池中的请求处理完成，
+// 发送完每个连接待发送的消息后关闭连接。
+// 超过ctx的截止时间时强制关闭剩余连接，并返回错误
+func (s *Server) Shutdown(ctx context.Context) error {
+	if !s.shuttingDown.CompareAndSwap(false, true) {
+		return ErrServerClosed
+	}
+
+	s.logger.Info("[SHUTDOWN] Server %s is shutting down", s.Name)
+
+	// 1. 停止接收新连接
+	s.closeListeners()
+
+	// 2. 停止心跳检测，避免关闭过程中误杀连接
 	if s.HeartbeatChecker != nil {
 		s.HeartbeatChecker.Stop()
 	}
 
-	// 停止工作池
+	var shutdownErr error
+
+	// 3. 等待工作池中的请求处理完成
 	if s.WorkerPool != nil {
-		s.WorkerPool.Stop()
+		if err := s.WorkerPool.Drain(ctx); err != nil {
+			shutdownErr = fmt.Errorf("drain worker pool: %w", err)
+		}
+	}
+
+	// 4. 发送每个连接中待发送的消息
+	if shutdownErr == nil {
+		s.connManager.Range(func(conn zinterface.IConnection) bool {
+			c, ok := conn.(*Connection)
+			if !ok {
+				return true
+			}
+			if err := c.Flush(ctx); err != nil {
+				shutdownErr = fmt.Errorf("flush connection %d: %w", c.GetConnId(), err)
+				return false
+			}
+			return true
+		})
+	}
+
+	// 5. 关闭所有连接，每个连接都会调用 OnConnStop
+	s.connManager.ClearConn()
+
+	s.release()
+
+	if shutdownErr != nil {
+		s.logger.Warn("[SHUTDOWN] Server %s shutdown incomplete: %v", s.Name, shutdownErr)
+		return shutdownErr
 	}
+	s.logger.Info("[SHUTDOWN] Server %s shutdown gracefully", s.Name)
+	return nil
+}
+
+// release 释放服务器持有的资源，只执行一次
+func (s *Server) release() {
+	s.closeOnce.Do(func() {
+		// 停止心跳检测器
+		if s.HeartbeatChecker != nil {
+			s.HeartbeatChecker.Stop()
+		}
 
-	// 通知退出
-	close(s.exitChan)
+		// 停止工作池
+		if s.WorkerPool != nil {
+			s.WorkerPool.Stop()
+		}
+
+		// 通知退出
+		close(s.exitChan)
+	})
 }
 
 // startMetricsReporter 启动性能指标报告器
@@ -159,7 +345,7 @@ func (s *Server) startMetricsReporter() {
 			select {
 			case <-ticker.C:
 				// 输出性能报告
-				utils.GlobalLogger.Info(utils.GlobalMetrics.GetMetricsReport())
+				s.logger.Info("%s", s.metrics.GetMetricsReport())
 			case <-s.exitChan:
 				return
 			}
@@ -167,17 +353,9 @@ func (s *Server) startMetricsReporter() {
 	}()
 }
 
-func NewServer() zinterface.IServer {
-	// 初始化性能指标收集器
-	utils.InitMetrics()
-	// 创建心跳检测器，每5秒检查一次，超时30秒
-	heartbeatChecker := NewHeartbeatChecker(5*time.Second, 30*time.Second)
-	heartbeatChecker.Start()
-
-	// 创建工作池，使用配置文件中的参数
-	workerPool := NewWorkerPool()
-	workerPool.Start()
-
+// NewServer 创建服务器，未通过opts设置的参数使用 utils.GlobalObject 中的配置
+// 每个服务器持有自己的路由、连接管理、工作池和心跳检测，同一进程中可以运行多个服务器
+func NewServer(opts ...Option) zinterface.IServer {
 	s := &Server{
 		Name:             utils.GlobalObject.Name,
 		IPVersion:        "tcp4",
@@ -185,13 +363,64 @@ func NewServer() zinterface.IServer {
 		Port:             utils.GlobalObject.TCPPort,
 		msgRouter:        NewMsgRouter(),
 		connManager:      NewConnManager(),
-		HeartbeatChecker: heartbeatChecker,
-		WorkerPool:       workerPool,
+		groupManager:     NewGroupManager(),
+		dataPack:         utils.NewDataPackUtil(),
+		heartbeat:        DefaultHeartbeatConfig(),
+		logger:           utils.GlobalLogger,
+		maxConn:          utils.GlobalObject.MaxConn,
+		sendQueueSize:    int(utils.GlobalObject.SendQueueSize),
+		maxPackageSize:   utils.GlobalObject.MaxPackageSize,
+		writeBufferSize:  int(utils.GlobalObject.WriteBufferSize),
+		workerPoolConfig: utils.GlobalObject.WorkerPool,
+		tlsFiles:         utils.GlobalObject.TLS,
 		exitChan:         make(chan struct{}),
+		ShutdownTimeout:  DefaultShutdownTimeout,
+		handleSignals:    true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.startErr = utils.GlobalObject.LoadErr()
+	if err := s.workerPoolConfig.Validate(); err != nil && s.startErr == nil {
+		s.startErr = err
+	}
+	for _, cfg := range s.pendingListeners {
+		if err := s.AddListener(cfg); err != nil && s.startErr == nil {
+			s.startErr = err
+		}
+	}
+	s.pendingListeners = nil
+	if s.tlsConfig == nil && s.tlsFiles.CertFile != "" {
+		if err := s.loadTLSFiles(); err != nil && s.startErr == nil {
+			s.startErr = err
+		}
 	}
+	if s.metrics == nil {
+		// 与其他服务器共用全局指标，不重新初始化，避免清空其他服务器记录的指标
+		s.metrics = utils.GlobalMetrics
+	}
+	if cm, ok := s.connManager.(*ConnManager); ok {
+		cm.logger = s.logger
+	}
+
+	// 创建心跳检测器，检测精度1秒，对端失效由心跳协议判断
+	// 默认心跳协议：客户端每5秒发送一次ping，30秒没有收到任何数据时关闭连接
+	s.HeartbeatChecker = NewHeartbeatChecker(time.Second, 0)
+	s.HeartbeatChecker.logger = s.logger
+	s.HeartbeatChecker.SetHeartbeat(s.heartbeat)
+	for state, timeout := range s.idleTimeouts {
+		s.HeartbeatChecker.SetIdleTimeout(IdleState(state), timeout)
+	}
+	s.HeartbeatChecker.SetOnReadIdle(s.idleHandlers[ReaderIdle])
+	s.HeartbeatChecker.SetOnWriteIdle(s.idleHandlers[WriterIdle])
+	s.HeartbeatChecker.SetOnAllIdle(s.idleHandlers[AllIdle])
+	s.HeartbeatChecker.Start()
 
-	// 添加心跳包处理
-	s.AddHandler(0, &HeartbeatHandler{})
+	// 创建工作池
+	s.WorkerPool = NewWorkerPoolWithConfig(s.workerPoolConfig)
+	s.WorkerPool.logger = s.logger
+	s.WorkerPool.metrics = s.metrics
+	s.WorkerPool.Start()
 
 	// 启动性能指标报告器
 	s.startMetricsReporter()
@@ -199,10 +428,68 @@ func NewServer() zinterface.IServer {
 	return s
 }
 
+// loadTLSFiles 从 tlsFiles 加载监听器的证书，按 ReloadInterval 检查证书文件是否修改
+func (s *Server) loadTLSFiles() error {
+	reloader, err := NewCertReloader(s.tlsFiles)
+	if err != nil {
+		return err
+	}
+	reloader.logger = s.logger
+	s.certReloader = reloader
+	s.tlsConfig = reloader.TLSConfig()
+
+	if s.tlsFiles.ReloadInterval > 0 {
+		go reloader.Watch(time.Duration(s.tlsFiles.ReloadInterval)*time.Second, s.exitChan)
+	}
+	return nil
+}
+
+// ReloadTLS 重新加载证书文件，已建立的连接不受影响
+// 只对从配置文件或 WithTLSFiles 加载的证书有效
+func (s *Server) ReloadTLS() error {
+	if s.certReloader == nil {
+		return errors.New("zinx: TLS certificates are not loaded from files")
+	}
+	if err := s.certReloader.Reload(); err != nil {
+		return err
+	}
+	s.logger.Info("Server %s TLS certificate reloaded", s.Name)
+	return nil
+}
+
+// SetHeartbeatConfig 设置心跳协议，为nil时关闭心跳，需要在 Start 之前调用
+// 心跳消息由连接直接处理，不经过路由，cfg.MsgId 不能再注册处理器
+func (s *Server) SetHeartbeatConfig(cfg *zinterface.HeartbeatConfig) {
+	s.heartbeat = cfg
+	if s.HeartbeatChecker != nil {
+		s.HeartbeatChecker.SetHeartbeat(cfg)
+	}
+}
+
+// GetHeartbeatConfig 获取心跳协议，未开启时返回nil
+func (s *Server) GetHeartbeatConfig() *zinterface.HeartbeatConfig {
+	return s.heartbeat
+}
+
+// SetDataPack 设置服务器使用的封包方式，需要在 Start 之前调用
+func (s *Server) SetDataPack(dp zinterface.IDataPack) {
+	s.dataPack = dp
+}
+
+// GetDataPack 获取服务器使用的封包方式
+func (s *Server) GetDataPack() zinterface.IDataPack {
+	return s.dataPack
+}
+
 func (s *Server) GetConnManager() zinterface.IConnManager {
 	return s.connManager
 }
 
+// GetGroupManager 获取连接分组管理器
+func (s *Server) GetGroupManager() zinterface.IGroupManager {
+	return s.groupManager
+}
+
 // GetWorkerPool 获取工作池
 func (s *Server) GetWorkerPool() interface{} {
 	return s.WorkerPool
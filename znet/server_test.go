@@ -0,0 +1,26 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"testing"
+)
+
+func TestNewServerKeepsGlobalMetrics(t *testing.T) {
+	global := utils.GlobalMetrics
+	s := NewServer(WithHeartbeat(nil)).(*Server)
+	defer s.Stop()
+
+	if utils.GlobalMetrics != global {
+		t.Fatal("NewServer replaced utils.GlobalMetrics")
+	}
+	if s.metrics != global {
+		t.Fatal("server without WithMetrics does not use utils.GlobalMetrics")
+	}
+
+	metrics := utils.NewMetrics()
+	other := NewServer(WithHeartbeat(nil), WithMetrics(metrics)).(*Server)
+	defer other.Stop()
+	if other.metrics != metrics || utils.GlobalMetrics != global {
+		t.Fatal("WithMetrics not applied or utils.GlobalMetrics replaced")
+	}
+}
@@ -0,0 +1,204 @@
+package znet
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// 时间轮参数，每层 wheelSlots 个槽，共 wheelLevels 层
+// tick为1秒时可以表示约194天的定时
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 4
+)
+
+// TimingWheel 分层时间轮
+// 添加和取消定时器都是O(1)，适合为大量连接维护超时；定时精度为tick
+type TimingWheel struct {
+	tick time.Duration
+	// 启动以来经过的tick数
+	current int64
+	// 每层的槽，第L层的一个槽跨越 wheelSlots^L 个tick
+	buckets [wheelLevels][wheelSlots]*list.List
+
+	mutex    sync.Mutex
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// WheelTimer 时间轮中的一个定时器
+type WheelTimer struct {
+	wheel *TimingWheel
+	// 到期的tick
+	expire int64
+	f      func()
+	// 所在的槽，为nil时表示已到期或已取消
+	bucket *list.List
+	elem   *list.Element
+}
+
+// NewTimingWheel 创建时间轮，tick为定时精度
+func NewTimingWheel(tick time.Duration) *TimingWheel {
+	if tick <= 0 {
+		tick = time.Second
+	}
+	tw := &TimingWheel{
+		tick:     tick,
+		stopChan: make(chan struct{}),
+	}
+	for level := range tw.buckets {
+		for slot := range tw.buckets[level] {
+			tw.buckets[level][slot] = list.New()
+		}
+	}
+	return tw
+}
+
+// Start 启动时间轮
+func (tw *TimingWheel) Start() {
+	tw.wg.Add(1)
+	go func() {
+		defer tw.wg.Done()
+
+		ticker := time.NewTicker(tw.tick)
+		defer ticker.Stop()
+
+		// 按实际经过的时间前进，ticker丢失的tick会被补上
+		start := time.Now()
+		var ticks int64
+		for {
+			select {
+			case now := <-ticker.C:
+				for target := int64(now.Sub(start) / tw.tick); ticks < target; ticks++ {
+					tw.advance()
+				}
+			case <-tw.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止时间轮，未到期的定时器不会再执行
+func (tw *TimingWheel) Stop() {
+	tw.stopOnce.Do(func() {
+		close(tw.stopChan)
+	})
+	tw.wg.Wait()
+}
+
+// AfterFunc 在d之后执行f，f在时间轮的协程中执行，不应阻塞
+func (tw *TimingWheel) AfterFunc(d time.Duration, f func()) *WheelTimer {
+	t := &WheelTimer{wheel: tw, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Reset 取消定时器并在d之后重新执行
+func (t *WheelTimer) Reset(d time.Duration) {
+	tw := t.wheel
+	// 向上取整，至少等待一个tick
+	ticks := int64((d + tw.tick - 1) / tw.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	t.remove()
+	t.expire = tw.current + ticks
+	tw.place(t)
+}
+
+// Stop 取消定时器，返回定时器是否在到期前被取消
+func (t *WheelTimer) Stop() bool {
+	t.wheel.mutex.Lock()
+	defer t.wheel.mutex.Unlock()
+	return t.remove()
+}
+
+// remove 将定时器从所在的槽中移除
+// 注意：调用此方法前必须持有tw.mutex锁
+func (t *WheelTimer) remove() bool {
+	if t.bucket == nil {
+		return false
+	}
+	t.bucket.Remove(t.elem)
+	t.bucket = nil
+	t.elem = nil
+	return true
+}
+
+// place 将定时器放入对应层的槽中
+// 注意：调用此方法前必须持有tw.mutex锁
+func (tw *TimingWheel) place(t *WheelTimer) {
+	delta := t.expire - tw.current
+	for level := 0; level < wheelLevels; level++ {
+		shift := uint(level * wheelBits)
+		expire := t.expire
+		if level == wheelLevels-1 && delta >= int64(wheelSlots)<<shift {
+			// 超过时间轮的范围，先放在最高层最远的槽，降级时重新计算
+			expire = tw.current + int64(wheelSlots-1)<<shift
+		}
+		if delta < int64(wheelSlots)<<shift || level == wheelLevels-1 {
+			t.bucket = tw.buckets[level][(expire>>shift)&wheelMask]
+			t.elem = t.bucket.PushBack(t)
+			return
+		}
+	}
+}
+
+// advance 前进一个tick，执行到期的定时器
+func (tw *TimingWheel) advance() {
+	tw.mutex.Lock()
+	tw.current++
+
+	// 低层转完一圈时，将高层当前槽中的定时器降级到低层
+	// 从高层向低层降级，保证降级后的定时器不会落入本次已经处理过的槽
+	top := 0
+	for level := 1; level < wheelLevels; level++ {
+		if tw.current&(int64(1)<<uint(level*wheelBits)-1) != 0 {
+			break
+		}
+		top = level
+	}
+	for level := top; level >= 1; level-- {
+		shift := uint(level * wheelBits)
+		tw.cascade(tw.buckets[level][(tw.current>>shift)&wheelMask])
+	}
+
+	// 取出第0层当前槽中到期的定时器
+	bucket := tw.buckets[0][tw.current&wheelMask]
+	var due []*WheelTimer
+	for e := bucket.Front(); e != nil; {
+		next := e.Next()
+		t := e.Value.(*WheelTimer)
+		if t.expire <= tw.current {
+			t.remove()
+			due = append(due, t)
+		}
+		e = next
+	}
+	tw.mutex.Unlock()
+
+	// 在锁外执行，f中可以重新设置定时器
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// cascade 将一个槽中的定时器重新放入时间轮
+// 注意：调用此方法前必须持有tw.mutex锁
+func (tw *TimingWheel) cascade(bucket *list.List) {
+	for e := bucket.Front(); e != nil; {
+		next := e.Next()
+		t := e.Value.(*WheelTimer)
+		t.remove()
+		tw.place(t)
+		e = next
+	}
+}
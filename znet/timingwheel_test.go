@@ -0,0 +1,186 @@
+package znet
+
+import (
+	"testing"
+	"time"
+)
+
+// advanceTo 在测试协程中手动推进时间轮到第target个tick
+func advanceTo(tw *TimingWheel, target int64) {
+	for tw.current < target {
+		tw.advance()
+	}
+}
+
+func TestTimingWheelCascade(t *testing.T) {
+	tw := NewTimingWheel(time.Second)
+	fired := make(map[int]int64)
+
+	// 覆盖每一层以及层与层之间的边界
+	delays := []int64{
+		1, 5, wheelSlots - 1, wheelSlots, wheelSlots + 1, 100,
+		wheelSlots * wheelSlots, wheelSlots*wheelSlots + 7, 5000,
+		wheelSlots * wheelSlots * wheelSlots, 300000,
+	}
+	for i, d := range delays {
+		i := i
+		tw.AfterFunc(time.Duration(d)*time.Second, func() {
+			fired[i] = tw.current
+		})
+	}
+
+	// advance 在测试协程中执行，定时器回调中可以直接读取 current
+	advanceTo(tw, delays[len(delays)-1]+1)
+	for i, d := range delays {
+		if got, ok := fired[i]; !ok || got != d {
+			t.Errorf("timer with delay %d fired at tick %d (fired = %t)", d, got, ok)
+		}
+	}
+}
+
+func TestTimingWheelCascadeFromOffset(t *testing.T) {
+	tw := NewTimingWheel(time.Second)
+	// 从不对齐的位置添加定时器，到期时间跨越高层槽的边界
+	advanceTo(tw, wheelSlots*wheelSlots-3)
+
+	fired := make(map[int]int64)
+	delays := []int64{2, 3, 4, wheelSlots + 2, wheelSlots*wheelSlots + 5}
+	start := tw.current
+	for i, d := range delays {
+		i := i
+		tw.AfterFunc(time.Duration(d)*time.Second, func() {
+			fired[i] = tw.current
+		})
+	}
+
+	advanceTo(tw, start+delays[len(delays)-1]+1)
+	for i, d := range delays {
+		if got, ok := fired[i]; !ok || got != start+d {
+			t.Errorf("timer with delay %d fired at tick %d (fired = %t), want %d", d, got, ok, start+d)
+		}
+	}
+}
+
+func TestTimingWheelBeyondRange(t *testing.T) {
+	if testing.Short() {
+		t.Skip("advances the wheel past its full range")
+	}
+	tw := NewTimingWheel(time.Second)
+	// 超过时间轮范围的定时器先放在最高层，降级时重新计算
+	d := int64(1)<<(wheelBits*wheelLevels) + 10
+	var firedAt int64 = -1
+	tw.AfterFunc(time.Duration(d)*time.Second, func() {
+		firedAt = tw.current
+	})
+
+	advanceTo(tw, d+1)
+	if firedAt != d {
+		t.Fatalf("timer with delay %d fired at tick %d", d, firedAt)
+	}
+}
+
+func TestTimingWheelStop(t *testing.T) {
+	tw := NewTimingWheel(time.Second)
+	var fired []string
+	short := tw.AfterFunc(3*time.Second, func() { fired = append(fired, "short") })
+	long := tw.AfterFunc(200*time.Second, func() { fired = append(fired, "long") })
+	kept := tw.AfterFunc(200*time.Second, func() { fired = append(fired, "kept") })
+
+	advanceTo(tw, 2)
+	if !short.Stop() {
+		t.Fatal("Stop before expire returned false")
+	}
+	// 取消已经降级过的高层定时器
+	advanceTo(tw, 150)
+	if !long.Stop() {
+		t.Fatal("Stop of cascaded timer returned false")
+	}
+	advanceTo(tw, 300)
+
+	if len(fired) != 1 || fired[0] != "kept" {
+		t.Fatalf("fired = %v, want [kept]", fired)
+	}
+	if kept.Stop() {
+		t.Fatal("Stop after expire returned true")
+	}
+	if short.Stop() {
+		t.Fatal("second Stop returned true")
+	}
+}
+
+func TestTimingWheelReset(t *testing.T) {
+	tw := NewTimingWheel(time.Second)
+	var fired []int64
+	timer := tw.AfterFunc(10*time.Second, func() { fired = append(fired, tw.current) })
+
+	// 到期前重置，从当前tick重新计算
+	advanceTo(tw, 8)
+	timer.Reset(100 * time.Second)
+	advanceTo(tw, 120)
+	if len(fired) != 1 || fired[0] != 108 {
+		t.Fatalf("fired at %v, want [108]", fired)
+	}
+
+	// 到期后可以再次使用，不足一个tick的时间向上取整
+	timer.Reset(1500 * time.Millisecond)
+	advanceTo(tw, 130)
+	if len(fired) != 2 || fired[1] != 122 {
+		t.Fatalf("fired at %v, want [108 122]", fired)
+	}
+	timer.Reset(0)
+	advanceTo(tw, 140)
+	if len(fired) != 3 || fired[2] != 131 {
+		t.Fatalf("fired at %v, want [108 122 131]", fired)
+	}
+}
+
+func TestTimingWheelResetInCallback(t *testing.T) {
+	tw := NewTimingWheel(time.Second)
+	var fired []int64
+	var timer *WheelTimer
+	timer = tw.AfterFunc(wheelSlots*time.Second, func() {
+		fired = append(fired, tw.current)
+		if len(fired) < 3 {
+			timer.Reset(wheelSlots * time.Second)
+		}
+	})
+
+	advanceTo(tw, 5*wheelSlots)
+	want := []int64{wheelSlots, 2 * wheelSlots, 3 * wheelSlots}
+	if len(fired) != len(want) {
+		t.Fatalf("fired at %v, want %v", fired, want)
+	}
+	for i := range want {
+		if fired[i] != want[i] {
+			t.Fatalf("fired at %v, want %v", fired, want)
+		}
+	}
+}
+
+func TestTimingWheelStartStop(t *testing.T) {
+	tw := NewTimingWheel(10 * time.Millisecond)
+	tw.Start()
+
+	done := make(chan struct{})
+	start := time.Now()
+	tw.AfterFunc(50*time.Millisecond, func() { close(done) })
+	select {
+	case <-done:
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Fatalf("timer fired after %v, want about 50ms", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer not fired")
+	}
+
+	// 停止后未到期的定时器不再执行
+	stopped := make(chan struct{})
+	tw.AfterFunc(30*time.Millisecond, func() { close(stopped) })
+	tw.Stop()
+	select {
+	case <-stopped:
+		t.Fatal("timer fired after the wheel stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+	tw.Stop()
+}
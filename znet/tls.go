@@ -0,0 +1,160 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TLSHandshakeTimeout 服务端TLS握手的超时时间
+const TLSHandshakeTimeout = 10 * time.Second
+
+// TLS连接的对端身份，证书验证通过后放入连接属性
+const (
+	// PropertyPeerCommonName 对端证书的 Subject.CommonName，string
+	PropertyPeerCommonName = "tls.peer.common_name"
+	// PropertyPeerDNSNames 对端证书的DNS SAN，[]string
+	PropertyPeerDNSNames = "tls.peer.dns_names"
+	// PropertyPeerCertificate 对端证书，*x509.Certificate
+	PropertyPeerCertificate = "tls.peer.certificate"
+)
+
+// IsPeerProperty 属性是否为 SetPeerIdentity 设置的对端身份
+func IsPeerProperty(key string) bool {
+	return strings.HasPrefix(key, "tls.peer.")
+}
+
+// CertReloader 从文件加载服务器证书和客户端CA，可以在不重启服务器的情况下重新加载
+// 已建立的连接不受影响，重新加载后的新连接使用新证书
+type CertReloader struct {
+	files  utils.TLSConfig
+	logger *utils.Logger
+
+	// 当前使用的配置
+	current atomic.Pointer[tls.Config]
+
+	// 上次加载时证书文件的修改时间
+	modTime time.Time
+	mutex   sync.Mutex
+}
+
+// NewCertReloader 加载证书文件，文件不存在或格式错误时返回错误
+func NewCertReloader(files utils.TLSConfig) (*CertReloader, error) {
+	if err := files.Validate(); err != nil {
+		return nil, err
+	}
+	if files.CertFile == "" {
+		return nil, errors.New("TLS.CertFile must not be empty")
+	}
+
+	r := &CertReloader{files: files, logger: utils.GlobalLogger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新加载证书文件，失败时继续使用之前的证书
+func (r *CertReloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	modTime := r.latestModTime()
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate %s error: %w", r.files.CertFile, err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if r.files.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.files.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("read TLS client CA %s error: %w", r.files.ClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("TLS client CA %s contains no certificates", r.files.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.files.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	r.current.Store(config)
+	r.modTime = modTime
+	return nil
+}
+
+// TLSConfig 获取用于监听器的TLS配置，每次握手时使用最新加载的证书
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// Watch 每隔interval检查证书文件是否修改，修改后重新加载，直到stop被关闭
+func (r *CertReloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.mutex.Lock()
+			changed := r.latestModTime().After(r.modTime)
+			r.mutex.Unlock()
+			if !changed {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Reload TLS certificate error: %v", err)
+				continue
+			}
+			r.logger.Info("TLS certificate %s reloaded", r.files.CertFile)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// latestModTime 获取证书相关文件中最晚的修改时间
+func (r *CertReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.files.CertFile, r.files.KeyFile, r.files.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// SetPeerIdentity 将验证通过的对端证书信息放入连接属性
+// 对端没有提供证书或证书未经验证时不做任何修改
+func SetPeerIdentity(conn zinterface.IConnection, state tls.ConnectionState) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return
+	}
+	leaf := state.VerifiedChains[0][0]
+	conn.SetProperty(PropertyPeerCommonName, leaf.Subject.CommonName)
+	conn.SetProperty(PropertyPeerDNSNames, leaf.DNSNames)
+	conn.SetProperty(PropertyPeerCertificate, leaf)
+}
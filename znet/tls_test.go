@@ -0,0 +1,330 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCA 测试时生成的自签名CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "zinx test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pool: pool,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue 签发localhost/127.0.0.1的证书，返回PEM格式的证书和私钥
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// clientCert 签发客户端证书
+func (ca *testCA) clientCert(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, cn, x509.ExtKeyUsageClientAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// writeServerFiles 签发服务器证书并写入dir，返回证书文件配置
+func (ca *testCA) writeServerFiles(t *testing.T, dir string, cn string) utils.TLSConfig {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, cn, x509.ExtKeyUsageServerAuth)
+	files := utils.TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	for name, data := range map[string][]byte{
+		files.CertFile:     certPEM,
+		files.KeyFile:      keyPEM,
+		files.ClientCAFile: ca.pem,
+	} {
+		if err := os.WriteFile(name, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+// startTLSServer 在随机端口启动使用证书文件的服务器，msgId 1 原样回复
+func startTLSServer(t *testing.T, files utils.TLSConfig, opts ...Option) (*Server, string) {
+	t.Helper()
+	opts = append([]Option{
+		WithAddress("127.0.0.1", 0),
+		WithTLSFiles(files),
+		WithHeartbeat(nil),
+	}, opts...)
+	s := NewServer(opts...).(*Server)
+	s.AddHandlerFunc(1, func(request zinterface.IRequest) {
+		request.GetConnection().SendMsg(1, request.GetMsgData())
+	})
+	if err := s.start(); err != nil {
+		t.Fatalf("start server: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	s.listenerLock.Lock()
+	addr := s.listeners[0].ln.Addr().String()
+	s.listenerLock.Unlock()
+	return s, addr
+}
+
+// echo 发送一条消息并读取回复
+func echo(conn net.Conn, data string) (string, error) {
+	dp := utils.NewDataPackUtil()
+	packed, err := dp.Pack(NewMsgPackage(1, []byte(data)))
+	if err != nil {
+		return "", err
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(packed); err != nil {
+		return "", err
+	}
+	msg, err := utils.ReadMessage(conn, dp)
+	if err != nil {
+		return "", err
+	}
+	return string(msg.GetData()), nil
+}
+
+func TestTLSRoundTrip(t *testing.T) {
+	ca := newTestCA(t)
+	_, addr := startTLSServer(t, ca.writeServerFiles(t, t.TempDir(), "server"))
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	got, err := echo(conn, "hello tls")
+	if err != nil {
+		t.Fatalf("echo: %v", err)
+	}
+	if got != "hello tls" {
+		t.Fatalf("echo = %q, want %q", got, "hello tls")
+	}
+}
+
+func TestTLSRequireClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	files := ca.writeServerFiles(t, t.TempDir(), "server")
+	files.RequireClientCert = true
+	metrics := utils.NewMetrics()
+	var starts, stops atomic.Int32
+	s, addr := startTLSServer(t, files,
+		WithMetrics(metrics),
+		WithOnConnStart(func(conn zinterface.IConnection) { starts.Add(1) }),
+		WithOnConnStop(func(conn zinterface.IConnection) { stops.Add(1) }),
+	)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool, ServerName: "localhost"})
+	if err == nil {
+		// TLS 1.3 中服务端在客户端握手完成后才验证客户端证书
+		_, err = echo(conn, "hello")
+		conn.Close()
+	}
+	if err == nil {
+		t.Fatal("client without certificate was accepted")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.GetConnManager().Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("rejected connection not removed from ConnManager")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if starts.Load() != 0 || stops.Load() != 0 {
+		t.Fatalf("OnConnStart called %d times, OnConnStop called %d times, want 0", starts.Load(), stops.Load())
+	}
+	if metrics.ConnectionsTotal != 0 || metrics.ConnectionsClosed != 0 {
+		t.Fatalf("connection metrics total = %d, closed = %d, want 0", metrics.ConnectionsTotal, metrics.ConnectionsClosed)
+	}
+}
+
+func TestTLSPeerIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	files := ca.writeServerFiles(t, t.TempDir(), "server")
+	files.RequireClientCert = true
+	peers := make(chan any, 1)
+	_, addr := startTLSServer(t, files, WithOnConnStart(func(conn zinterface.IConnection) {
+		cn, _ := conn.GetProperty(PropertyPeerCommonName)
+		peers <- cn
+	}))
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:      ca.pool,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{ca.clientCert(t, "client-1")},
+	})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := echo(conn, "hello"); err != nil {
+		t.Fatalf("echo: %v", err)
+	}
+
+	select {
+	case cn := <-peers:
+		if cn != "client-1" {
+			t.Fatalf("peer common name in OnConnStart = %v, want client-1", cn)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnConnStart not called")
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	s, addr := startTLSServer(t, ca.writeServerFiles(t, dir, "server-1"))
+
+	serverName := func() string {
+		t.Helper()
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool, ServerName: "localhost"})
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := serverName(); got != "server-1" {
+		t.Fatalf("server certificate = %q, want server-1", got)
+	}
+
+	ca.writeServerFiles(t, dir, "server-2")
+	if err := s.ReloadTLS(); err != nil {
+		t.Fatalf("ReloadTLS: %v", err)
+	}
+	if got := serverName(); got != "server-2" {
+		t.Fatalf("server certificate after ReloadTLS = %q, want server-2", got)
+	}
+}
+
+func TestTLSFilesWithListener(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	s, addr := startTLSServer(t, ca.writeServerFiles(t, dir, "server-1"), WithListener(zinterface.ListenerConfig{
+		Name:    "public",
+		Network: "tcp4",
+		Address: "127.0.0.1:0",
+	}))
+
+	dial := func() *tls.Conn {
+		t.Helper()
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool, ServerName: "localhost"})
+		if err != nil {
+			t.Fatalf("dial TLS: %v", err)
+		}
+		return conn
+	}
+
+	conn := dial()
+	if got, err := echo(conn, "hello"); err != nil || got != "hello" {
+		t.Fatalf("echo = %q, %v", got, err)
+	}
+	conn.Close()
+
+	// 明文连接无法通信
+	plain, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer plain.Close()
+	if _, err := echo(plain, "hello"); err == nil {
+		t.Fatal("plaintext client accepted by listener using TLS files")
+	}
+
+	// ReloadTLS 对添加的监听器同样生效
+	ca.writeServerFiles(t, dir, "server-2")
+	if err := s.ReloadTLS(); err != nil {
+		t.Fatalf("ReloadTLS: %v", err)
+	}
+	conn = dial()
+	defer conn.Close()
+	if got := conn.ConnectionState().PeerCertificates[0].Subject.CommonName; got != "server-2" {
+		t.Fatalf("server certificate after ReloadTLS = %q, want server-2", got)
+	}
+}
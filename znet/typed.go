@@ -0,0 +1,81 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// StatusError 带状态码的错误，类型化处理器返回该错误时使用其状态码回复
+type StatusError interface {
+	error
+	Status() uint16
+}
+
+type requestCtxKey struct{}
+
+// WithRequest 将请求保存到ctx中
+func WithRequest(ctx context.Context, req zinterface.IRequest) context.Context {
+	return context.WithValue(ctx, requestCtxKey{}, req)
+}
+
+// RequestFromContext 从ctx中获取请求
+func RequestFromContext(ctx context.Context) (zinterface.IRequest, bool) {
+	req, ok := ctx.Value(requestCtxKey{}).(zinterface.IRequest)
+	return req, ok
+}
+
+// Handle 注册一个类型化的处理器
+// fn的ctx派生自连接的context，连接关闭时被取消
+// 使用codec将消息体解码为Req并调用fn，codec为nil时使用处理时路由中该msgId的编解码器，fn返回的Resp编码后通过 IRequest.Reply 回复；
+// Resp为nil时不回复。解码失败时交给 IMsgRouter.OnDecodeError 处理，
+// fn返回错误时回复错误响应，错误实现了 StatusError 时使用其状态码。
+// 封包方式不传输序列号和标志位时不自动回复，fn返回的错误只记录日志
+func Handle[Req any, Resp any](router zinterface.IMsgRouter, msgId uint32, codec zinterface.ICodec,
+	fn func(ctx context.Context, conn zinterface.IConnection, req *Req) (*Resp, error)) {
+	router.AddHandlerFunc(msgId, func(request zinterface.IRequest) {
+		// 每次处理时获取编解码器，注册后调用的 SetCodec/SetMsgCodec 同样生效
+		codec := codec
+		if codec == nil {
+			codec = router.GetCodec(msgId)
+		}
+
+		req := new(Req)
+		if err := codec.Unmarshal(request.GetMsgData(), req); err != nil {
+			router.OnDecodeError(request, fmt.Errorf("%s decode msgId = %d: %w", codec.Name(), msgId, err))
+			return
+		}
+
+		ctx := WithRequest(request.GetConnection().Context(), request)
+		resp, err := fn(ctx, request.GetConnection(), req)
+		// 响应在对端看来是一个新的请求，不能自动回复
+		if !utils.CarriesSeq(request.GetConnection().GetDataPack()) {
+			if err != nil {
+				loggerOf(request.GetConnection()).Warn("connID = %d msgId = %d handler error: %v",
+					request.GetConnection().GetConnId(), msgId, err)
+			}
+			return
+		}
+		if err != nil {
+			status := zinterface.StatusInternalError
+			var se StatusError
+			if errors.As(err, &se) {
+				status = se.Status()
+			}
+			request.ReplyError(status, []byte(err.Error()))
+			return
+		}
+		if resp == nil {
+			return
+		}
+
+		data, err := codec.Marshal(resp)
+		if err != nil {
+			request.ReplyError(zinterface.StatusInternalError, []byte(fmt.Sprintf("%s encode msgId = %d: %v", codec.Name(), msgId, err)))
+			return
+		}
+		request.Reply(data)
+	})
+}
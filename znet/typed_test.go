@@ -0,0 +1,36 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandleReplyWithSeq(t *testing.T) {
+	_, b, connA, connB := newPipeEndpoints(t, WithDataPack(utils.NewExtDataPack()))
+	Handle(b.msgRouter, 1, nil, func(ctx context.Context, conn zinterface.IConnection, req *echoReq) (*echoReq, error) {
+		return &echoReq{Name: "hello " + req.Name}, nil
+	})
+	connA.Start()
+	connB.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := connA.Call(ctx, 1, []byte(`{"Name":"zinx"}`))
+	if err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if string(resp) != `{"Name":"hello zinx"}` {
+		t.Fatalf("Call = %s, want {\"Name\":\"hello zinx\"}", resp)
+	}
+
+	_, err = connA.Call(ctx, 1, []byte("not json"))
+	var callErr *CallError
+	if !errors.As(err, &callErr) || callErr.Status != zinterface.StatusBadRequest {
+		t.Fatalf("Call with bad payload = %v, want StatusBadRequest", err)
+	}
+}
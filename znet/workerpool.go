@@ -3,7 +3,10 @@ package znet
 import (
 	"Go_Zinx/utils"
 	"Go_Zinx/zinterface"
+	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,6 +21,8 @@ type Worker struct {
 	lastActivity time.Time
 	isCore       bool
 	wg           sync.WaitGroup
+	// 所属工作池中未处理完的请求数
+	pending *int64
 }
 
 // NewWorker 创建新的工作线程
@@ -49,6 +54,9 @@ func (w *Worker) Start() {
 				w.lastActivity = time.Now()
 				// 处理消息请求
 				request.GetConnection().GetRouter().DoMsgHandler(request)
+				if w.pending != nil {
+					atomic.AddInt64(w.pending, -1)
+				}
 			// 接收停止信号
 			case <-w.stopChan:
 				w.isStopped = true
@@ -83,8 +91,16 @@ type WorkerPool struct {
 	currentWorkers uint32
 	// 工作线程池
 	WorkerPool chan chan zinterface.IRequest
-	// 请求队列
+	// 请求队列，即 PriorityNormal 的队列
 	JobQueue chan zinterface.IRequest
+	// 按优先级划分的请求队列，下标见 laneHigh/laneNormal/laneLow
+	lanes [laneCount]chan zinterface.IRequest
+	// 各优先级队列的大小
+	laneSizes [laneCount]uint32
+	// 低优先级队列连续被跳过的次数，只在调度协程中访问
+	skipped [laneCount]uint32
+	// 饥饿保护阈值
+	starvationLimit uint32
 	// 工作线程集合
 	workers map[uint32]*Worker
 	// 互斥锁，保护工作池的并发访问
@@ -103,8 +119,28 @@ type WorkerPool struct {
 	timer *time.Timer
 	// 定时器是否已启动
 	timerStarted bool
+	// 已入队但还未处理完的请求数
+	pending int64
+	// 是否正在排空，排空期间拒绝新请求
+	draining atomic.Bool
+	// 调度模式
+	mode string
+	// ordered 模式下每个分片的请求队列
+	shards []chan zinterface.IRequest
+	// ordered 模式下计算分片的函数，为nil时使用ConnID
+	shardKey atomic.Pointer[ShardKeyFunc]
+	// 请求队列满时的处理策略
+	overflowPolicy string
+	// 请求队列满时由应用决定处理策略，为nil时使用 overflowPolicy
+	overflowHandler atomic.Pointer[OverflowHandler]
+	// 日志和性能指标，默认为全局实例，由所属Server替换
+	logger  *utils.Logger
+	metrics *utils.Metrics
 }
 
+// OverflowHandler 请求队列满时调用，返回该请求使用的处理策略（utils.OverflowXxx）
+type OverflowHandler func(request zinterface.IRequest) string
+
 // NewWorkerPool 创建新的工作池
 // 使用默认配置
 func NewWorkerPool() *WorkerPool {
@@ -114,21 +150,27 @@ func NewWorkerPool() *WorkerPool {
 // NewWorkerPoolWithConfig 创建新的工作池
 // 使用自定义配置
 func NewWorkerPoolWithConfig(config utils.WorkerPoolConfig) *WorkerPool {
-	return &WorkerPool{
+	wp := &WorkerPool{
 		coreWorkers:       config.CoreWorkers,
 		maxWorkers:        config.MaxWorkers,
 		queueSize:         config.QueueSize,
 		idleTimeout:       time.Duration(config.IdleTimeout) * time.Second,
 		currentWorkers:    0,
 		WorkerPool:        make(chan chan zinterface.IRequest, config.MaxWorkers),
-		JobQueue:          make(chan zinterface.IRequest, config.QueueSize),
 		workers:           make(map[uint32]*Worker),
 		stopChan:          make(chan bool),
 		isStopped:         false,
 		nextWorkerID:      1,
 		dispatcherStarted: false,
 		timerStarted:      false,
+		mode:              config.Mode,
+		overflowPolicy:    config.OverflowPolicy,
+		starvationLimit:   config.StarvationLimit,
+		logger:            utils.GlobalLogger,
+		metrics:           utils.GlobalMetrics,
 	}
+	wp.initLanes(config)
+	return wp
 }
 
 // Start 启动工作池
@@ -147,6 +189,12 @@ func (wp *WorkerPool) Start() {
 		go wp.dispatch()
 	}
 
+	// ordered 模式使用固定数量的分片工作线程
+	if wp.mode == utils.DispatchOrdered {
+		wp.startShards()
+		return
+	}
+
 	// 启动核心工作线程（快速启动）
 	for wp.currentWorkers < wp.coreWorkers {
 		wp.createWorker()
@@ -169,12 +217,13 @@ func (wp *WorkerPool) createWorker() {
 	// 判断是否是核心工作线程
 	isCore := wp.currentWorkers < wp.coreWorkers
 	worker := NewWorker(workerID, wp.WorkerPool, isCore)
+	worker.pending = &wp.pending
 	worker.Start()
 
 	wp.workers[workerID] = worker
 	wp.currentWorkers++
 
-	utils.GlobalLogger.Info("Worker %d started (core: %t), current workers: %d", workerID, isCore, wp.currentWorkers)
+	wp.logger.Info("Worker %d started (core: %t), current workers: %d", workerID, isCore, wp.currentWorkers)
 }
 
 // dispatch 任务调度器
@@ -183,21 +232,25 @@ func (wp *WorkerPool) dispatch() {
 	defer wp.wg.Done()
 
 	for {
-		select {
-		// 接收请求
-		case request := <-wp.JobQueue:
-			wp.handleRequest(request)
-		// 接收停止信号
-		case <-wp.stopChan:
+		// 按优先级接收请求
+		request, ok := wp.nextRequest()
+		if !ok {
+			// 接收到停止信号
 			wp.mutex.Lock()
 			for _, worker := range wp.workers {
 				worker.Stop()
 			}
 			wp.isStopped = true
 			wp.mutex.Unlock()
-			utils.GlobalLogger.Info("WorkerPool dispatcher stopped")
+			wp.logger.Info("WorkerPool dispatcher stopped")
 			return
 		}
+
+		if wp.mode == utils.DispatchOrdered {
+			wp.dispatchOrdered(request)
+		} else {
+			wp.handleRequest(request)
+		}
 	}
 }
 
@@ -209,7 +262,7 @@ func (wp *WorkerPool) handleRequest(request zinterface.IRequest) {
 	// 尝试获取一个可用的工作线程
 	case workerJobQueue := <-wp.WorkerPool:
 		// 将请求分配给工作线程
-		workerJobQueue <- request
+		wp.assign(workerJobQueue, request)
 	default:
 		// 没有可用的工作线程，检查是否可以创建新的工作线程
 		wp.mutex.RLock()
@@ -221,36 +274,167 @@ func (wp *WorkerPool) handleRequest(request zinterface.IRequest) {
 			wp.mutex.Lock()
 			wp.createWorker()
 			wp.mutex.Unlock()
-			// 等待新的工作线程注册到WorkerPool
-			workerJobQueue := <-wp.WorkerPool
-			workerJobQueue <- request
-		} else {
-			// 已经达到最大工作线程数，阻塞等待可用的工作线程
-			workerJobQueue := <-wp.WorkerPool
-			workerJobQueue <- request
+		}
+
+		// 等待新的工作线程注册到WorkerPool，
+		// 已经达到最大工作线程数时，阻塞等待可用的工作线程
+		select {
+		case workerJobQueue := <-wp.WorkerPool:
+			wp.assign(workerJobQueue, request)
+		case <-wp.stopChan:
+			atomic.AddInt64(&wp.pending, -1)
 		}
 	}
 }
 
+// assign 将请求交给工作线程，工作池停止时放弃该请求
+func (wp *WorkerPool) assign(workerJobQueue chan zinterface.IRequest, request zinterface.IRequest) {
+	select {
+	case workerJobQueue <- request:
+	case <-wp.stopChan:
+		atomic.AddInt64(&wp.pending, -1)
+	}
+}
+
 // AddRequest 添加请求到工作池
 func (wp *WorkerPool) AddRequest(request zinterface.IRequest) {
 	// 如果工作池已经停止，拒绝请求
 	wp.mutex.RLock()
 	if wp.isStopped {
 		wp.mutex.RUnlock()
-		utils.GlobalLogger.Warn("WorkerPool is stopped, request rejected")
+		wp.logger.Warn("WorkerPool is stopped, request rejected")
 		return
 	}
+	shards := wp.shards
 	wp.mutex.RUnlock()
 
-	// 尝试添加请求到队列
+	// 正在排空，拒绝新请求
+	if wp.draining.Load() {
+		wp.logger.Warn("WorkerPool is draining, request rejected")
+		return
+	}
+
+	// 尝试添加请求到对应优先级的队列
+	// ordered 模式直接放入分片队列，溢出策略按分片生效，一个分片满时不影响其他分片；
+	// 同一分片内按到达顺序处理，不区分优先级
+	queue := wp.lanes[wp.laneOf(request)]
+	if len(shards) > 0 {
+		queue = wp.shardOf(request, shards)
+	}
+	atomic.AddInt64(&wp.pending, 1)
 	select {
-	case wp.JobQueue <- request:
+	case queue <- request:
 		// 请求添加成功
 	default:
-		// 队列已满，记录警告
-		utils.GlobalLogger.Warn("WorkerPool job queue is full, request rejected")
+		// 队列已满，按策略处理
+		wp.handleOverflow(request, queue)
+	}
+}
+
+// SetOverflowHandler 设置请求队列满时的回调，由应用决定每个请求的处理策略
+func (wp *WorkerPool) SetOverflowHandler(f OverflowHandler) {
+	if f == nil {
+		wp.overflowHandler.Store(nil)
+		return
 	}
+	wp.overflowHandler.Store(&f)
+}
+
+// GetOverflowPolicy 获取请求队列满时的默认处理策略
+func (wp *WorkerPool) GetOverflowPolicy() string {
+	if wp.overflowPolicy == "" {
+		return utils.OverflowDropNewest
+	}
+	return wp.overflowPolicy
+}
+
+// handleOverflow 请求队列已满时按策略处理请求，调用前请求已计入pending
+func (wp *WorkerPool) handleOverflow(request zinterface.IRequest, queue chan zinterface.IRequest) {
+	policy := wp.GetOverflowPolicy()
+	if f := wp.overflowHandler.Load(); f != nil {
+		policy = (*f)(request)
+	}
+
+	switch policy {
+	case utils.OverflowBlock:
+		// 阻塞读协程，直到队列有空位
+		select {
+		case queue <- request:
+		case <-wp.stopChan:
+			atomic.AddInt64(&wp.pending, -1)
+		}
+
+	case utils.OverflowDropOldest:
+		for {
+			select {
+			case queue <- request:
+				return
+			default:
+			}
+			// 取出同一队列中最早的请求丢弃，再次尝试入队
+			select {
+			case oldest := <-queue:
+				atomic.AddInt64(&wp.pending, -1)
+				wp.reject(oldest, "oldest request dropped")
+				wp.replyBusy(oldest)
+			default:
+			}
+		}
+
+	case utils.OverflowClose:
+		atomic.AddInt64(&wp.pending, -1)
+		wp.reject(request, "closing connection")
+		request.GetConnection().Stop()
+
+	default:
+		atomic.AddInt64(&wp.pending, -1)
+		wp.reject(request, "request dropped")
+		wp.replyBusy(request)
+	}
+}
+
+// replyBusy 不阻塞地回复 StatusUnavailable，发送队列已满时放弃回复并计数
+// 封包方式不传输标志位时对端会把回复当作新的请求，过载时只会产生更多请求，不回复
+func (wp *WorkerPool) replyBusy(request zinterface.IRequest) {
+	if !utils.CarriesSeq(request.GetConnection().GetDataPack()) {
+		return
+	}
+	if err := tryReplyError(request, zinterface.StatusUnavailable, []byte("server busy")); errors.Is(err, ErrSendQueueFull) {
+		wp.metrics.IncrementRejectReplySkipped()
+	}
+}
+
+// reject 记录被拒绝的请求
+func (wp *WorkerPool) reject(request zinterface.IRequest, reason string) {
+	wp.logger.Warn("WorkerPool job queue is full, %s, connID = %d msgId = %d",
+		reason, request.GetConnection().GetConnId(), request.GetMsgID())
+	wp.metrics.IncrementRejected(request.GetMsgID())
+}
+
+// Drain 停止接收新请求，并等待已入队的请求全部处理完成
+// 超过ctx的截止时间时返回错误
+func (wp *WorkerPool) Drain(ctx context.Context) error {
+	wp.draining.Store(true)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&wp.pending) > 0 {
+		select {
+		case <-ctx.Done():
+			wp.logger.Warn("WorkerPool drain timeout, %d requests unfinished", atomic.LoadInt64(&wp.pending))
+			return ctx.Err()
+		case <-wp.stopChan:
+			return nil
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// GetPendingSize 获取已入队但还未处理完的请求数
+func (wp *WorkerPool) GetPendingSize() int64 {
+	return atomic.LoadInt64(&wp.pending)
 }
 
 // checkIdleWorkers 检查并销毁空闲的非核心工作线程
@@ -300,7 +484,7 @@ func (wp *WorkerPool) checkIdleWorkersImpl() {
 		worker.Stop()
 		delete(wp.workers, workerID)
 		wp.currentWorkers--
-		utils.GlobalLogger.Info("Worker %d stopped due to idle timeout, current workers: %d", workerID, wp.currentWorkers)
+		wp.logger.Info("Worker %d stopped due to idle timeout, current workers: %d", workerID, wp.currentWorkers)
 	}
 }
 
@@ -320,16 +504,14 @@ func (wp *WorkerPool) Stop() {
 		worker.Stop()
 	}
 
-	// 清空请求队列
-	close(wp.JobQueue)
-
+	// 不关闭请求队列，避免并发的AddRequest向已关闭的通道发送数据
 	wp.isStopped = true
 	wp.mutex.Unlock()
 
 	// 等待所有goroutine结束
 	wp.wg.Wait()
 
-	utils.GlobalLogger.Info("WorkerPool stopped, total workers: %d", len(wp.workers))
+	wp.logger.Info("WorkerPool stopped, total workers: %d", len(wp.workers))
 }
 
 // GetWorkerSize 获取当前工作线程数
@@ -354,6 +536,14 @@ func (wp *WorkerPool) GetQueueSize() uint32 {
 	return wp.queueSize
 }
 
+// GetMode 获取调度模式
+func (wp *WorkerPool) GetMode() string {
+	if wp.mode == "" {
+		return utils.DispatchElastic
+	}
+	return wp.mode
+}
+
 // GetIdleTimeout 获取空闲超时时间
 func (wp *WorkerPool) GetIdleTimeout() time.Duration {
 	return wp.idleTimeout
@@ -0,0 +1,79 @@
+package znet
+
+import (
+	"Go_Zinx/zinterface"
+	"sync/atomic"
+)
+
+// ShardKeyFunc 计算请求的分片key，key相同的请求按到达顺序处理
+type ShardKeyFunc func(request zinterface.IRequest) uint64
+
+// SetShardKeyFunc 设置 ordered 模式下的分片函数，为nil时按ConnID分片
+// 例如按登录后的用户ID分片，可以保证同一用户在多个连接上的请求也按顺序处理
+func (wp *WorkerPool) SetShardKeyFunc(f ShardKeyFunc) {
+	if f == nil {
+		wp.shardKey.Store(nil)
+		return
+	}
+	wp.shardKey.Store(&f)
+}
+
+// startShards 启动 ordered 模式的分片工作线程
+// 注意：调用此方法前必须持有wp.mutex锁
+func (wp *WorkerPool) startShards() {
+	if len(wp.shards) > 0 {
+		return
+	}
+
+	shardCount := wp.maxWorkers
+	if shardCount == 0 {
+		shardCount = 1
+	}
+
+	wp.shards = make([]chan zinterface.IRequest, shardCount)
+	for i := range wp.shards {
+		wp.shards[i] = make(chan zinterface.IRequest, wp.queueSize)
+		wp.wg.Add(1)
+		go wp.runShard(uint32(i), wp.shards[i])
+	}
+	wp.currentWorkers = shardCount
+
+	wp.logger.Info("WorkerPool started in ordered mode, shards: %d", shardCount)
+}
+
+// runShard 按顺序处理一个分片中的请求
+func (wp *WorkerPool) runShard(shardID uint32, queue chan zinterface.IRequest) {
+	defer wp.wg.Done()
+
+	for {
+		select {
+		case request := <-queue:
+			request.GetConnection().GetRouter().DoMsgHandler(request)
+			atomic.AddInt64(&wp.pending, -1)
+		case <-wp.stopChan:
+			wp.logger.Info("Shard worker %d stopped", shardID)
+			return
+		}
+	}
+}
+
+// shardOf 获取请求所在分片的队列
+func (wp *WorkerPool) shardOf(request zinterface.IRequest, shards []chan zinterface.IRequest) chan zinterface.IRequest {
+	var key uint64
+	if f := wp.shardKey.Load(); f != nil {
+		key = (*f)(request)
+	} else {
+		key = uint64(request.GetConnection().GetConnId())
+	}
+	return shards[key%uint64(len(shards))]
+}
+
+// dispatchOrdered 将工作池启动前进入优先级队列的请求放入对应分片的队列，分片队列满时阻塞等待
+// 启动后 AddRequest 直接放入分片队列，不经过调度协程
+func (wp *WorkerPool) dispatchOrdered(request zinterface.IRequest) {
+	select {
+	case wp.shardOf(request, wp.shards) <- request:
+	case <-wp.stopChan:
+		atomic.AddInt64(&wp.pending, -1)
+	}
+}
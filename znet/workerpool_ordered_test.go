@@ -0,0 +1,148 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPoolConns 创建 n 个属于 s 的连接，ConnID 从1开始，连接不启动
+func newPoolConns(t *testing.T, s *Server, n int) []*Connection {
+	t.Helper()
+	conns := make([]*Connection, n)
+	for i := range conns {
+		local, remote := net.Pipe()
+		conns[i] = NewConnection(s, local, uint32(i+1), s.msgRouter)
+		t.Cleanup(func() {
+			local.Close()
+			remote.Close()
+		})
+	}
+	return conns
+}
+
+// waitPending 等待工作池处理完所有请求
+func waitPending(t *testing.T, wp *WorkerPool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for wp.GetPendingSize() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests not handled", wp.GetPendingSize())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorkerPoolOrderedPerConnection(t *testing.T) {
+	const n = 500
+	s := NewServer(WithHeartbeat(nil)).(*Server)
+	defer s.Stop()
+
+	var mu sync.Mutex
+	got := make(map[uint32][]uint32)
+	s.AddHandlerFunc(1, func(request zinterface.IRequest) {
+		seq := binary.LittleEndian.Uint32(request.GetMsgData())
+		// 处理时间不同，分片之间交错执行
+		if seq%7 == 0 {
+			time.Sleep(50 * time.Microsecond)
+		}
+		mu.Lock()
+		id := request.GetConnection().GetConnId()
+		got[id] = append(got[id], seq)
+		mu.Unlock()
+	})
+
+	wp := NewWorkerPoolWithConfig(utils.WorkerPoolConfig{
+		CoreWorkers: 4,
+		MaxWorkers:  4,
+		QueueSize:   2 * n,
+		IdleTimeout: 1,
+		Mode:        utils.DispatchOrdered,
+	})
+	wp.Start()
+	defer wp.Stop()
+
+	conns := newPoolConns(t, s, 3)
+	for seq := uint32(0); seq < n; seq++ {
+		for _, conn := range conns {
+			data := binary.LittleEndian.AppendUint32(nil, seq)
+			wp.AddRequest(NewRequest(conn, NewMsgPackage(1, data)))
+		}
+	}
+	waitPending(t, wp)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, conn := range conns {
+		seqs := got[conn.GetConnId()]
+		if len(seqs) != n {
+			t.Fatalf("conn %d handled %d requests, want %d", conn.GetConnId(), len(seqs), n)
+		}
+		for i, seq := range seqs {
+			if seq != uint32(i) {
+				t.Fatalf("conn %d request %d has seq %d, want %d", conn.GetConnId(), i, seq, i)
+			}
+		}
+	}
+}
+
+func TestWorkerPoolElasticScaling(t *testing.T) {
+	s := NewServer(WithHeartbeat(nil)).(*Server)
+	defer s.Stop()
+
+	started := make(chan struct{}, 8)
+	release := make(chan struct{})
+	s.AddHandlerFunc(1, func(request zinterface.IRequest) {
+		started <- struct{}{}
+		<-release
+	})
+
+	wp := NewWorkerPoolWithConfig(utils.WorkerPoolConfig{
+		CoreWorkers: 1,
+		MaxWorkers:  3,
+		QueueSize:   16,
+		IdleTimeout: 1,
+	})
+	wp.Start()
+	defer wp.Stop()
+	if got := wp.GetWorkerSize(); got != 1 {
+		t.Fatalf("workers after Start = %d, want 1", got)
+	}
+
+	// 所有工作线程都在处理请求时创建新的工作线程，最多 MaxWorkers 个
+	conn := newPoolConns(t, s, 1)[0]
+	for i := 0; i < 4; i++ {
+		wp.AddRequest(NewRequest(conn, NewMsgPackage(1, nil)))
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d requests started", i)
+		}
+	}
+	select {
+	case <-started:
+		t.Fatal("more requests running than MaxWorkers")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if got := wp.GetWorkerSize(); got != 3 {
+		t.Fatalf("workers under load = %d, want 3", got)
+	}
+
+	close(release)
+	waitPending(t, wp)
+
+	// 空闲超时后回收非核心工作线程
+	deadline := time.Now().Add(5 * time.Second)
+	for wp.GetWorkerSize() > 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("workers after idle timeout = %d, want 1", wp.GetWorkerSize())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
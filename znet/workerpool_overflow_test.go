@@ -0,0 +1,228 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+	"net"
+	"testing"
+	"time"
+)
+
+// newFullPool 创建不启动的工作池，并用 msgId 1..size 的请求填满普通优先级队列
+func newFullPool(t *testing.T, conn zinterface.IConnection, policy string) *WorkerPool {
+	t.Helper()
+	wp := NewWorkerPoolWithConfig(utils.WorkerPoolConfig{
+		CoreWorkers:    1,
+		MaxWorkers:     1,
+		QueueSize:      2,
+		IdleTimeout:    1,
+		OverflowPolicy: policy,
+	})
+	wp.metrics = utils.NewMetrics()
+	t.Cleanup(wp.Stop)
+	for id := uint32(1); id <= 2; id++ {
+		wp.AddRequest(NewRequest(conn, NewMsgPackage(id, nil)))
+	}
+	return wp
+}
+
+// queuedIDs 取出普通优先级队列中所有请求的msgId
+func queuedIDs(wp *WorkerPool) []uint32 {
+	var ids []uint32
+	for {
+		select {
+		case request := <-wp.lanes[laneNormal]:
+			ids = append(ids, request.GetMsgID())
+		default:
+			return ids
+		}
+	}
+}
+
+func equalIDs(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	_, conn := newTestConn(t)
+	wp := newFullPool(t, conn, "")
+
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(3, nil)))
+	if got := wp.metrics.GetRejected(3); got != 1 {
+		t.Fatalf("rejected msgId 3 = %d, want 1", got)
+	}
+	if got := wp.GetPendingSize(); got != 2 {
+		t.Fatalf("pending = %d, want 2", got)
+	}
+	if got := queuedIDs(wp); !equalIDs(got, []uint32{1, 2}) {
+		t.Fatalf("queued = %v, want [1 2]", got)
+	}
+	if !conn.IsAlive() {
+		t.Fatal("connection closed by drop_newest")
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	_, conn := newTestConn(t)
+	wp := newFullPool(t, conn, utils.OverflowDropOldest)
+
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(3, nil)))
+	if got := wp.metrics.GetRejected(1); got != 1 {
+		t.Fatalf("rejected msgId 1 = %d, want 1", got)
+	}
+	if got := wp.metrics.GetRejected(3); got != 0 {
+		t.Fatalf("rejected msgId 3 = %d, want 0", got)
+	}
+	if got := wp.GetPendingSize(); got != 2 {
+		t.Fatalf("pending = %d, want 2", got)
+	}
+	if got := queuedIDs(wp); !equalIDs(got, []uint32{2, 3}) {
+		t.Fatalf("queued = %v, want [2 3]", got)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	_, conn := newTestConn(t)
+	wp := newFullPool(t, conn, utils.OverflowBlock)
+
+	done := make(chan struct{})
+	go func() {
+		wp.AddRequest(NewRequest(conn, NewMsgPackage(3, nil)))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("AddRequest returned while the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 队列有空位后请求入队
+	if request := <-wp.lanes[laneNormal]; request.GetMsgID() != 1 {
+		t.Fatalf("first request msgId = %d, want 1", request.GetMsgID())
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AddRequest still blocked after the queue has room")
+	}
+	if got := queuedIDs(wp); !equalIDs(got, []uint32{2, 3}) {
+		t.Fatalf("queued = %v, want [2 3]", got)
+	}
+	if got := wp.metrics.GetRejected(3); got != 0 {
+		t.Fatalf("rejected msgId 3 = %d, want 0", got)
+	}
+}
+
+func TestOverflowBlockStop(t *testing.T) {
+	_, conn := newTestConn(t)
+	wp := newFullPool(t, conn, utils.OverflowBlock)
+
+	done := make(chan struct{})
+	go func() {
+		wp.AddRequest(NewRequest(conn, NewMsgPackage(3, nil)))
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// 停止工作池时阻塞的请求被放弃
+	wp.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AddRequest still blocked after Stop")
+	}
+}
+
+func TestOverflowClose(t *testing.T) {
+	_, conn := newTestConn(t)
+	wp := newFullPool(t, conn, utils.OverflowClose)
+
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(3, nil)))
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed by close policy")
+	}
+	if got := wp.metrics.GetRejected(3); got != 1 {
+		t.Fatalf("rejected msgId 3 = %d, want 1", got)
+	}
+	if got := wp.GetPendingSize(); got != 2 {
+		t.Fatalf("pending = %d, want 2", got)
+	}
+}
+
+func TestOverflowHandler(t *testing.T) {
+	_, conn := newTestConn(t)
+	wp := newFullPool(t, conn, utils.OverflowClose)
+
+	// 回调的返回值优先于默认策略
+	wp.SetOverflowHandler(func(request zinterface.IRequest) string {
+		if request.GetMsgID() == 3 {
+			return utils.OverflowDropOldest
+		}
+		return utils.OverflowDropNewest
+	})
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(3, nil)))
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(4, nil)))
+
+	if !conn.IsAlive() {
+		t.Fatal("connection closed although the handler chose another policy")
+	}
+	if wp.metrics.GetRejected(1) != 1 || wp.metrics.GetRejected(4) != 1 {
+		t.Fatalf("rejected msgId 1 = %d, msgId 4 = %d, want 1 and 1",
+			wp.metrics.GetRejected(1), wp.metrics.GetRejected(4))
+	}
+	if got := queuedIDs(wp); !equalIDs(got, []uint32{2, 3}) {
+		t.Fatalf("queued = %v, want [2 3]", got)
+	}
+
+	// 清除回调后恢复默认策略
+	wp.SetOverflowHandler(nil)
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(5, nil)))
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(6, nil)))
+	wp.AddRequest(NewRequest(conn, NewMsgPackage(7, nil)))
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after the handler was removed")
+	}
+}
+
+func TestOverflowReplyBusy(t *testing.T) {
+	dp := utils.NewExtDataPack()
+	s := NewServer(WithHeartbeat(nil), WithDataPack(dp)).(*Server)
+	local, remote := net.Pipe()
+	conn := NewConnection(s, local, 1, s.msgRouter)
+	conn.Start()
+	t.Cleanup(func() {
+		conn.Stop()
+		remote.Close()
+		s.Stop()
+	})
+	wp := newFullPool(t, conn, utils.OverflowDropNewest)
+
+	msg := NewMsgPackage(3, nil)
+	msg.Seq = 42
+	wp.AddRequest(NewRequest(conn, msg))
+
+	// 被丢弃的请求收到 StatusUnavailable 错误响应
+	remote.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := utils.ReadMessage(remote, dp)
+	if err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	if reply.GetMsgId() != 3 || reply.GetSeq() != 42 {
+		t.Fatalf("reply msgId = %d seq = %d, want 3 and 42", reply.GetMsgId(), reply.GetSeq())
+	}
+	if reply.GetFlags()&zinterface.FlagError == 0 || reply.GetStatus() != zinterface.StatusUnavailable {
+		t.Fatalf("reply flags = %#x status = %d, want error with StatusUnavailable", reply.GetFlags(), reply.GetStatus())
+	}
+}
@@ -0,0 +1,133 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"Go_Zinx/zinterface"
+)
+
+// 优先级队列的下标，下标越小优先级越高
+const (
+	laneHigh = iota
+	laneNormal
+	laneLow
+	laneCount
+)
+
+// DefaultStarvationLimit 低优先级队列连续被跳过的默认上限
+const DefaultStarvationLimit = 8
+
+// laneIndex 获取优先级对应的队列下标
+func laneIndex(priority zinterface.Priority) int {
+	switch {
+	case priority > zinterface.PriorityNormal:
+		return laneHigh
+	case priority < zinterface.PriorityNormal:
+		return laneLow
+	default:
+		return laneNormal
+	}
+}
+
+// lanePriority 获取队列下标对应的优先级
+func lanePriority(lane int) zinterface.Priority {
+	return zinterface.PriorityHigh - zinterface.Priority(lane)
+}
+
+// initLanes 创建各优先级的请求队列
+func (wp *WorkerPool) initLanes(config utils.WorkerPoolConfig) {
+	wp.laneSizes[laneHigh] = config.HighQueueSize
+	wp.laneSizes[laneNormal] = config.QueueSize
+	wp.laneSizes[laneLow] = config.LowQueueSize
+	for lane := range wp.lanes {
+		if wp.laneSizes[lane] == 0 {
+			wp.laneSizes[lane] = config.QueueSize
+		}
+		wp.lanes[lane] = make(chan zinterface.IRequest, wp.laneSizes[lane])
+	}
+	wp.JobQueue = wp.lanes[laneNormal]
+
+	if wp.starvationLimit == 0 {
+		wp.starvationLimit = DefaultStarvationLimit
+	}
+}
+
+// laneOf 根据路由中msgId的优先级获取请求的队列下标
+func (wp *WorkerPool) laneOf(request zinterface.IRequest) int {
+	router := request.GetConnection().GetRouter()
+	if router == nil {
+		return laneNormal
+	}
+	return laneIndex(router.GetMsgPriority(request.GetMsgID()))
+}
+
+// nextRequest 按优先级取出下一个请求，工作池停止时返回false
+// 高优先级队列中的请求先被处理；为避免饥饿，较低优先级的队列在有请求等待时
+// 连续被跳过 starvationLimit 次后，会先处理一个它的请求
+// 只用于 elastic 模式：ordered 模式的请求直接进入连接所在的分片，不经过优先级队列，msgId的优先级不生效
+func (wp *WorkerPool) nextRequest() (zinterface.IRequest, bool) {
+	select {
+	case <-wp.stopChan:
+		return nil, false
+	default:
+	}
+
+	// 饥饿保护，从最低优先级开始检查
+	for lane := laneCount - 1; lane > laneHigh; lane-- {
+		if wp.skipped[lane] < wp.starvationLimit {
+			continue
+		}
+		wp.skipped[lane] = 0
+		select {
+		case request := <-wp.lanes[lane]:
+			return wp.take(lane, request), true
+		default:
+		}
+	}
+
+	// 按优先级从高到低取请求
+	for lane := range wp.lanes {
+		select {
+		case request := <-wp.lanes[lane]:
+			return wp.take(lane, request), true
+		default:
+		}
+	}
+
+	// 所有队列都为空，等待任意一个队列的请求
+	select {
+	case request := <-wp.lanes[laneHigh]:
+		return wp.take(laneHigh, request), true
+	case request := <-wp.lanes[laneNormal]:
+		return wp.take(laneNormal, request), true
+	case request := <-wp.lanes[laneLow]:
+		return wp.take(laneLow, request), true
+	case <-wp.stopChan:
+		return nil, false
+	}
+}
+
+// take 记录从lane取出了一个请求，比lane优先级低且有请求等待的队列跳过次数加一
+func (wp *WorkerPool) take(lane int, request zinterface.IRequest) zinterface.IRequest {
+	for lower := lane + 1; lower < laneCount; lower++ {
+		if len(wp.lanes[lower]) > 0 {
+			wp.skipped[lower]++
+		}
+	}
+	wp.metrics.IncrementLaneDispatched(lanePriority(lane).String())
+	return request
+}
+
+// GetLaneQueueSize 获取指定优先级的请求队列大小
+func (wp *WorkerPool) GetLaneQueueSize(priority zinterface.Priority) uint32 {
+	return wp.laneSizes[laneIndex(priority)]
+}
+
+// GetLaneLength 获取指定优先级的队列中等待分发的请求数
+func (wp *WorkerPool) GetLaneLength(priority zinterface.Priority) int {
+	return len(wp.lanes[laneIndex(priority)])
+}
+
+// GetStarvationLimit 获取低优先级队列连续被跳过的上限
+func (wp *WorkerPool) GetStarvationLimit() uint32 {
+	return wp.starvationLimit
+}
@@ -0,0 +1,63 @@
+package znet
+
+import (
+	"Go_Zinx/utils"
+	"testing"
+)
+
+func TestWorkerPoolStarvationLimit(t *testing.T) {
+	wp := NewWorkerPoolWithConfig(utils.WorkerPoolConfig{
+		CoreWorkers:     1,
+		MaxWorkers:      1,
+		QueueSize:       16,
+		IdleTimeout:     1,
+		StarvationLimit: 2,
+	})
+	wp.metrics = utils.NewMetrics()
+
+	// 不启动工作池，直接填充队列后按调度顺序取出
+	for i := 0; i < 8; i++ {
+		wp.lanes[laneHigh] <- NewRequest(nil, NewMsgPackage(1, nil))
+	}
+	for i := 0; i < 3; i++ {
+		wp.lanes[laneLow] <- NewRequest(nil, NewMsgPackage(3, nil))
+	}
+
+	// 低优先级队列每被跳过2次处理一个请求
+	want := []uint32{1, 1, 3, 1, 1, 3, 1, 1, 3, 1, 1}
+	for i, id := range want {
+		request, ok := wp.nextRequest()
+		if !ok {
+			t.Fatalf("nextRequest %d returned false", i)
+		}
+		if request.GetMsgID() != id {
+			t.Fatalf("request %d msgId = %d, want %d", i, request.GetMsgID(), id)
+		}
+	}
+	if got := wp.metrics.GetLaneDispatched("low"); got != 3 {
+		t.Fatalf("low lane dispatched %d, want 3", got)
+	}
+}
+
+func TestWorkerPoolOrderedRejectsPriorities(t *testing.T) {
+	config := utils.WorkerPoolConfig{
+		CoreWorkers: 1,
+		MaxWorkers:  1,
+		QueueSize:   16,
+		IdleTimeout: 1,
+		Mode:        utils.DispatchOrdered,
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate ordered config: %v", err)
+	}
+
+	config.StarvationLimit = 4
+	if err := config.Validate(); err == nil {
+		t.Fatal("Validate accepted priorities in ordered mode")
+	}
+	s := NewServer(WithHeartbeat(nil), WithWorkerPoolConfig(config)).(*Server)
+	defer s.Stop()
+	if err := s.start(); err == nil {
+		t.Fatal("server started with priorities in ordered mode")
+	}
+}